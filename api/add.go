@@ -1,10 +1,40 @@
 package api
 
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// ErrInvalidURL is returned when a URL to be added is not an absolute http or
+// https URL. URLs without a scheme are rejected rather than guessed at.
+var ErrInvalidURL = errors.New("invalid URL")
+
 // AddOption is the options for the Add API.
 type AddOption struct {
 	URL   string `json:"url,omitempty"`
 	Title string `json:"title,omitempty"`
-	Tags  string `json:"tags,omitempty"`
+	// Tags is a comma-separated list of tags.
+	//
+	// Deprecated: Use TagList, which cannot mistake a comma in a tag for a
+	// separator.
+	Tags string `json:"tags,omitempty"`
+	// TagList, when not nil, is sent in place of Tags. Tags containing
+	// commas are rejected with ErrInvalidTag, as Pocket cannot escape them.
+	TagList []string `json:"-"`
+	// Time is the unix time the item was saved at, for preserving original
+	// dates when importing. Zero means now.
+	Time int64 `json:"time,omitempty"`
+}
+
+// joinedTags returns the tags to send, from TagList or Tags.
+func (o *AddOption) joinedTags() (string, error) {
+	if o.TagList == nil {
+		return o.Tags, nil
+	}
+	return joinTags(o.TagList)
 }
 
 type addAPIOptionWithAuth struct {
@@ -12,23 +42,137 @@ type addAPIOptionWithAuth struct {
 	authInfo
 }
 
-// AddResult is a struct with no fields, which is passed in below to conform
-// to the interface for PostJSON.
-type AddResult struct{}
+// AddResult is the item Pocket created for an added URL.
+type AddResult struct {
+	ItemID      int64  `json:"item_id,string"`
+	NormalURL   string `json:"normal_url"`
+	ResolvedURL string `json:"resolved_url"`
+	Title       string `json:"title"`
+}
+
+type addAPIResult struct {
+	Item   *AddResult `json:"item"`
+	Status int        `json:"status"`
+}
+
+// Add saves a URL to Pocket and returns the item it created.
+func (c *Client) Add(options *AddOption) (*AddResult, error) {
+	return c.AddContext(context.Background(), options)
+}
+
+// AddContext is like Add, but the request is bound to ctx so it can be
+// cancelled or given a deadline.
+func (c *Client) AddContext(ctx context.Context, options *AddOption) (*AddResult, error) {
+	if options == nil {
+		return nil, fmt.Errorf("%w: no URL given", ErrInvalidURL)
+	}
+	if err := validateURL(options.URL); err != nil {
+		return nil, err
+	}
+	tags, err := options.joinedTags()
+	if err != nil {
+		return nil, err
+	}
 
-// Add only returns an error status, since adding an article doesn't have
-// any other meaningful return value.
-func (c *Client) Add(options *AddOption) error {
+	option := *options
+	option.Tags = tags
 	data := addAPIOptionWithAuth{
 		authInfo:  c.authInfo,
-		AddOption: options,
+		AddOption: &option,
 	}
 
-	res := &AddResult{}
-	err := PostJSON("/v3/add", data, res)
+	res := &addAPIResult{}
+	err = c.postJSON(ctx, "/add", data, res)
 	if err != nil {
-		return nil
+		return nil, err
+	}
+
+	if res.Item == nil {
+		return &AddResult{}, nil
+	}
+
+	return res.Item, nil
+}
+
+func validateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: %q must be an http or https URL", ErrInvalidURL, rawURL)
+	}
+
+	return nil
+}
+
+// AddBatch adds all of the URLs using add actions of the send API, in
+// requests of at most BatchSize actions. The returned slice is aligned by index
+// with options and holds nil for each URL which could not be added; URLs or
+// tags which fail validation are not sent at all. When the rate limit is
+// exhausted between requests, AddBatch waits for it to be replenished; set
+// RetryPolicy to also retry the requests which hit it.
+func (c *Client) AddBatch(options []*AddOption) ([]*AddResult, error) {
+	return c.AddBatchContext(context.Background(), options)
+}
+
+// AddBatchContext is like AddBatch, but the requests are bound to ctx so they
+// can be cancelled or given a deadline. If a request fails, the results of
+// the requests made before it are returned along with the error.
+func (c *Client) AddBatchContext(ctx context.Context, options []*AddOption) ([]*AddResult, error) {
+	results := make([]*AddResult, len(options))
+
+	actions := []*Action{}
+	indexes := []int{}
+	for i, option := range options {
+		if option == nil || validateURL(option.URL) != nil {
+			continue
+		}
+		action, err := NewAddAction(option)
+		if err != nil {
+			continue
+		}
+		actions = append(actions, action)
+		indexes = append(indexes, i)
+	}
+
+	size := c.BatchSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+
+	for start := 0; start < len(actions); start += size {
+		end := start + size
+		if end > len(actions) {
+			end = len(actions)
+		}
+
+		if start > 0 {
+			if limit := c.LastRateLimit(); isRateLimited(limit) {
+				if err := sleepContext(ctx, rateLimitDelay(limit)); err != nil {
+					return results, err
+				}
+			}
+		}
+
+		res, err := c.ModifyContext(ctx, actions[start:end]...)
+		if err != nil {
+			return results, err
+		}
+
+		for j, raw := range res.rawResults {
+			if start+j >= end || !res.ActionResults[j] {
+				continue
+			}
+
+			item := &AddResult{}
+			if err := json.Unmarshal(raw, item); err != nil {
+				item = &AddResult{}
+			}
+			results[indexes[start+j]] = item
+		}
 	}
 
-	return err
+	return results, nil
 }
@@ -0,0 +1,306 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bvp/go-pocket/api"
+	"github.com/bvp/go-pocket/api/apitest"
+	. "github.com/onsi/gomega"
+)
+
+func TestAddReportsErrors(t *testing.T) {
+	RegisterTestingT(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Error", "Missing API parameters")
+		w.WriteHeader(400)
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+	_, err := client.Add(&api.AddOption{URL: "https://example.com/"})
+
+	Expect(err).NotTo(BeNil())
+}
+
+func TestAddContextCancel(t *testing.T) {
+	RegisterTestingT(t)
+
+	started := make(chan struct{})
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-done
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err := client.AddContext(ctx, &api.AddOption{URL: "https://example.com/"})
+
+	Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+	Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+}
+
+func TestAddResult(t *testing.T) {
+	RegisterTestingT(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"item":{
+				"item_id":"229279689",
+				"normal_url":"http://example.com/article",
+				"resolved_id":"229279689",
+				"resolved_url":"https://example.com/article",
+				"domain_id":"1234",
+				"title":"An Article",
+				"word_count":"832",
+				"has_image":"0",
+				"has_video":"0"
+			},
+			"status":1
+		}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+	res, err := client.Add(&api.AddOption{URL: "https://example.com/article"})
+
+	Expect(err).To(BeNil())
+	Expect(res).To(Equal(&api.AddResult{
+		ItemID:      229279689,
+		NormalURL:   "http://example.com/article",
+		ResolvedURL: "https://example.com/article",
+		Title:       "An Article",
+	}))
+}
+
+func TestAddTime(t *testing.T) {
+	RegisterTestingT(t)
+
+	var data map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data = nil
+		json.NewDecoder(r.Body).Decode(&data)
+		w.Write([]byte(`{"item":{"item_id":"1"},"status":1}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+
+	_, err := client.Add(&api.AddOption{URL: "https://example.com/", Time: 1262304000})
+	Expect(err).To(BeNil())
+	Expect(data).To(HaveKeyWithValue("time", float64(1262304000)))
+
+	_, err = client.Add(&api.AddOption{URL: "https://example.com/"})
+	Expect(err).To(BeNil())
+	Expect(data).NotTo(HaveKey("time"))
+}
+
+func TestAddTagList(t *testing.T) {
+	RegisterTestingT(t)
+
+	server := apitest.NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	_, err := client.Add(&api.AddOption{URL: "https://example.com/1", TagList: []string{"go", "two words"}})
+	Expect(err).To(BeNil())
+
+	_, err = client.Add(&api.AddOption{URL: "https://example.com/2", TagList: []string{"go", "a,b"}})
+	Expect(errors.Is(err, api.ErrInvalidTag)).To(BeTrue())
+
+	results, err := client.AddBatch([]*api.AddOption{
+		{URL: "https://example.com/3", TagList: []string{"a,b"}},
+		{URL: "https://example.com/4", TagList: []string{"cli"}, Tags: "ignored"},
+	})
+	Expect(err).To(BeNil())
+	Expect(results[0]).To(BeNil())
+	Expect(results[1]).NotTo(BeNil())
+
+	items := server.Items()
+	Expect(items).To(HaveLen(2))
+	Expect(items[0].TagNames()).To(Equal([]string{"go", "two words"}))
+	Expect(items[1].URL()).To(Equal("https://example.com/4"))
+	Expect(items[1].TagNames()).To(Equal([]string{"cli"}))
+}
+
+func TestAddValidatesURL(t *testing.T) {
+	RegisterTestingT(t)
+
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"item":{"item_id":"1"},"status":1}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+
+	for _, u := range []string{"", "notaurl", "example.com/article", "ftp://example.com/", "https://"} {
+		_, err := client.Add(&api.AddOption{URL: u})
+		Expect(errors.Is(err, api.ErrInvalidURL)).To(BeTrue(), u)
+	}
+	_, err := client.Add(nil)
+	Expect(errors.Is(err, api.ErrInvalidURL)).To(BeTrue())
+	Expect(requests).To(Equal(0))
+
+	for _, u := range []string{"http://example.com", "https://example.com/article?id=1"} {
+		_, err := client.Add(&api.AddOption{URL: u})
+		Expect(err).To(BeNil(), u)
+	}
+	Expect(requests).To(Equal(2))
+}
+
+func TestAddBatch(t *testing.T) {
+	RegisterTestingT(t)
+
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Expect(r.URL.Path).To(Equal("/v3/send"))
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Write([]byte(`{
+			"action_results":[
+				{"item_id":"101","normal_url":"http://example.com/a","resolved_url":"https://example.com/a","title":"A"},
+				false
+			],
+			"status":1
+		}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+	results, err := client.AddBatch([]*api.AddOption{
+		{URL: "https://example.com/a", Tags: "go"},
+		{URL: "not a url"},
+		{URL: "https://example.com/gone"},
+	})
+
+	Expect(err).To(BeNil())
+	Expect(body).To(ContainSubstring(`{"action":"add","tags":"go","url":"https://example.com/a"}`))
+	Expect(body).To(ContainSubstring(`{"action":"add","url":"https://example.com/gone"}`))
+	Expect(body).NotTo(ContainSubstring(`not a url`))
+
+	Expect(results).To(HaveLen(3))
+	Expect(results[0]).To(Equal(&api.AddResult{
+		ItemID:      101,
+		NormalURL:   "http://example.com/a",
+		ResolvedURL: "https://example.com/a",
+		Title:       "A",
+	}))
+	Expect(results[1]).To(BeNil())
+	Expect(results[2]).To(BeNil())
+}
+
+// batchServer answers every add action of a send request with the created
+// item, numbering items in the order they arrive.
+func batchServer(sizes *[]int, header http.Header) *httptest.Server {
+	next := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Actions []*api.Action `json:"actions"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		*sizes = append(*sizes, len(body.Actions))
+
+		results := []map[string]interface{}{}
+		for range body.Actions {
+			next++
+			results = append(results, map[string]interface{}{"item_id": strconv.Itoa(next)})
+		}
+		for name, values := range header {
+			w.Header()[name] = values
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"action_results": results, "status": 1})
+	}))
+}
+
+func TestAddBatchChunks(t *testing.T) {
+	RegisterTestingT(t)
+
+	var sizes []int
+	ts := batchServer(&sizes, nil)
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+
+	options := []*api.AddOption{}
+	for i := 0; i < 250; i++ {
+		options = append(options, &api.AddOption{URL: fmt.Sprintf("https://example.com/%d", i)})
+	}
+
+	results, err := client.AddBatch(options)
+	Expect(err).To(BeNil())
+	Expect(sizes).To(Equal([]int{100, 100, 50}))
+	Expect(results).To(HaveLen(250))
+	for i, res := range results {
+		Expect(res.ItemID).To(Equal(int64(i + 1)))
+	}
+
+	sizes = nil
+	client.BatchSize = 2
+	_, err = client.AddBatch(options[:5])
+	Expect(err).To(BeNil())
+	Expect(sizes).To(Equal([]int{2, 2, 1}))
+}
+
+func TestAddBatchWaitsForRateLimit(t *testing.T) {
+	RegisterTestingT(t)
+
+	var sizes []int
+	ts := batchServer(&sizes, http.Header{
+		"X-Limit-User-Limit":     {"320"},
+		"X-Limit-User-Remaining": {"0"},
+		"X-Limit-User-Reset":     {"3600"},
+	})
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+	client.BatchSize = 1
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	results, err := client.AddBatchContext(ctx, []*api.AddOption{
+		{URL: "https://example.com/a"},
+		{URL: "https://example.com/b"},
+	})
+	Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+	Expect(sizes).To(Equal([]int{1}))
+	Expect(results[0].ItemID).To(Equal(int64(1)))
+	Expect(results[1]).To(BeNil())
+}
@@ -2,9 +2,14 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 )
 
 // Origin is the constant origin URL for the Pocket API
@@ -13,9 +18,87 @@ var Origin = "https://getpocket.com"
 // DefaultClient is the client used for making all requests
 var DefaultClient = http.DefaultClient
 
+// DefaultUserAgent is the User-Agent sent with requests unless a Client sets
+// its own.
+const DefaultUserAgent = "go-pocket/0.1"
+
+// DefaultBatchSize is the number of actions AddBatch sends per request unless
+// Client.BatchSize says otherwise.
+const DefaultBatchSize = 100
+
 // Client represents a Pocket client that grants OAuth access to your application
 type Client struct {
 	authInfo
+	httpClient *http.Client
+
+	// BaseURL is the URL every API path is appended to. It defaults to
+	// Origin + "/v3", and can be changed to route requests through a proxy.
+	BaseURL string
+
+	// UserAgent is sent as the User-Agent header of every request. It
+	// defaults to DefaultUserAgent.
+	UserAgent string
+
+	// RetryPolicy, when set, retries requests which fail because Pocket is
+	// unavailable or the rate limit was hit.
+	RetryPolicy *RetryPolicy
+
+	// BatchSize is the most actions AddBatch sends in one request. It
+	// defaults to DefaultBatchSize.
+	BatchSize int
+
+	// OnResponse, when set, is called after every request the client makes,
+	// including each retry, to let it be logged.
+	OnResponse func(*ResponseInfo)
+
+	mu            sync.Mutex
+	lastRateLimit RateLimit
+}
+
+// ResponseInfo describes a request made by a Client and how it went, for
+// Client.OnResponse.
+type ResponseInfo struct {
+	Method string
+	URL    string
+
+	// StatusCode is zero when no response was received.
+	StatusCode int
+	Duration   time.Duration
+
+	// Err is the error the request failed with, if any.
+	Err error
+}
+
+// APIError is returned when Pocket responds with an unsuccessful status. Code
+// and Message come from the X-Error-Code and X-Error response headers.
+type APIError struct {
+	StatusCode int
+	Code       int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("got response %d; X-Error-Code=[%d] X-Error=[%s]", e.StatusCode, e.Code, e.Message)
+}
+
+// ErrUnauthorized is matched by the APIError of a request Pocket rejected
+// because the access token is invalid, for instance because the user revoked
+// it. A new access token has to be obtained.
+var ErrUnauthorized = errors.New("not authorized")
+
+// Is reports whether e matches target, making errors.Is(err, ErrUnauthorized)
+// true for a 401 response.
+func (e *APIError) Is(target error) bool {
+	return target == ErrUnauthorized && e.StatusCode == http.StatusUnauthorized
+}
+
+func newAPIError(resp *http.Response) *APIError {
+	code, _ := strconv.Atoi(resp.Header.Get("X-Error-Code"))
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Code:       code,
+		Message:    resp.Header.Get("X-Error"),
+	}
 }
 
 type authInfo struct {
@@ -23,44 +106,123 @@ type authInfo struct {
 	AccessToken string `json:"access_token"`
 }
 
-// NewClient creates a new Pocket client.
+// NewClient creates a new Pocket client which makes its requests with
+// DefaultClient.
 func NewClient(consumerKey, accessToken string) *Client {
+	return NewClientWithHTTP(consumerKey, accessToken, nil)
+}
+
+// NewClientWithHTTP creates a new Pocket client which makes its requests with
+// hc, allowing timeouts, proxies or custom transports to be configured. A nil
+// hc uses DefaultClient.
+func NewClientWithHTTP(consumerKey, accessToken string, hc *http.Client) *Client {
 	return &Client{
 		authInfo: authInfo{
 			ConsumerKey: consumerKey,
 			AccessToken: accessToken,
 		},
+		httpClient: hc,
+		BaseURL:    Origin + "/v3",
+		UserAgent:  DefaultUserAgent,
+		BatchSize:  DefaultBatchSize,
 	}
 }
 
-func doJSON(req *http.Request, res interface{}) error {
+func (c *Client) postJSON(ctx context.Context, path string, data, res interface{}) error {
+	hc := c.httpClient
+	if hc == nil {
+		hc = DefaultClient
+	}
+
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		resp, err := postJSON(ctx, hc, c.BaseURL+path, c.UserAgent, data, res)
+		if c.OnResponse != nil {
+			info := &ResponseInfo{
+				Method:   "POST",
+				URL:      c.BaseURL + path,
+				Duration: time.Since(start),
+				Err:      err,
+			}
+			if resp != nil {
+				info.StatusCode = resp.StatusCode
+			}
+			c.OnResponse(info)
+		}
+
+		var limit RateLimit
+		if resp != nil {
+			limit = parseRateLimit(resp)
+			c.mu.Lock()
+			c.lastRateLimit = limit
+			c.mu.Unlock()
+		}
+
+		if !c.RetryPolicy.shouldRetry(attempt, resp, err) {
+			return err
+		}
+
+		if err := sleepContext(ctx, c.RetryPolicy.delay(attempt, limit)); err != nil {
+			return err
+		}
+	}
+}
+
+// doJSON sends req and decodes the response body into res. The response is
+// returned, with its body already closed, whenever one was received.
+func doJSON(hc *http.Client, req *http.Request, res interface{}) (*http.Response, error) {
 	req.Header.Add("X-Accept", "application/json")
 	req.Header.Add("Content-Type", "application/json")
 
-	resp, err := DefaultClient.Do(req)
+	resp, err := hc.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("got response %d; X-Error=[%s]", resp.StatusCode, resp.Header.Get("X-Error"))
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp, newAPIError(resp)
 	}
 
-	defer resp.Body.Close()
-	return json.NewDecoder(resp.Body).Decode(res)
+	return resp, json.NewDecoder(resp.Body).Decode(res)
 }
 
 // PostJSON posts the data to the API endpoint, storing the result in res.
 func PostJSON(action string, data, res interface{}) error {
+	return PostJSONContext(context.Background(), action, data, res)
+}
+
+// PostJSONContext is like PostJSON, but the request is bound to ctx.
+func PostJSONContext(ctx context.Context, action string, data, res interface{}) error {
+	return PostJSONWithHTTP(ctx, DefaultClient, action, data, res)
+}
+
+// PostJSONWithHTTP is like PostJSONContext, but the request is made with hc.
+// A nil hc uses DefaultClient.
+func PostJSONWithHTTP(ctx context.Context, hc *http.Client, action string, data, res interface{}) error {
+	if hc == nil {
+		hc = DefaultClient
+	}
+
+	_, err := postJSON(ctx, hc, Origin+action, DefaultUserAgent, data, res)
+	return err
+}
+
+func postJSON(ctx context.Context, hc *http.Client, url, userAgent string, data, res interface{}) (*http.Response, error) {
 	body, err := json.Marshal(data)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", Origin+action, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
 	}
 
-	return doJSON(req, res)
+	return doJSON(hc, req, res)
 }
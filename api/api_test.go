@@ -0,0 +1,182 @@
+package api_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bvp/go-pocket/api"
+	. "github.com/onsi/gomega"
+)
+
+type stubTransport struct {
+	requests []*http.Request
+	body     string
+}
+
+func (t *stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests = append(t.requests, req)
+	return &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader(t.body)),
+		Request:    req,
+	}, nil
+}
+
+func TestNewClientWithHTTP(t *testing.T) {
+	RegisterTestingT(t)
+
+	api.Origin = "https://pocket.invalid"
+
+	transport := &stubTransport{body: `{"action_results":[true],"status":1}`}
+	client := api.NewClientWithHTTP("consumer", "token", &http.Client{Transport: transport})
+
+	res, err := client.Modify(api.NewArchiveAction(1))
+
+	Expect(err).To(BeNil())
+	Expect(res.ActionResults).To(Equal([]bool{true}))
+	Expect(transport.requests).To(HaveLen(1))
+	Expect(transport.requests[0].URL.String()).To(Equal("https://pocket.invalid/v3/send"))
+}
+
+func TestClientBaseURL(t *testing.T) {
+	RegisterTestingT(t)
+
+	var path string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Write([]byte(`{"list":{},"status":1}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = "https://pocket.invalid"
+
+	client := api.NewClient("consumer", "token")
+	Expect(client.BaseURL).To(Equal("https://pocket.invalid/v3"))
+
+	client.BaseURL = ts.URL + "/gateway/pocket"
+	_, err := client.Retrieve(&api.RetrieveOption{})
+
+	Expect(err).To(BeNil())
+	Expect(path).To(Equal("/gateway/pocket/get"))
+}
+
+func TestClientOnResponse(t *testing.T) {
+	RegisterTestingT(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v3/send" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"list":{},"status":1}`))
+	}))
+	defer ts.Close()
+
+	client := api.NewClient("consumer", "token")
+	client.BaseURL = ts.URL + "/v3"
+
+	infos := []*api.ResponseInfo{}
+	client.OnResponse = func(info *api.ResponseInfo) {
+		infos = append(infos, info)
+	}
+
+	_, err := client.Retrieve(&api.RetrieveOption{})
+	Expect(err).To(BeNil())
+	Expect(infos).To(HaveLen(1))
+	Expect(infos[0].Method).To(Equal("POST"))
+	Expect(infos[0].URL).To(Equal(ts.URL + "/v3/get"))
+	Expect(infos[0].StatusCode).To(Equal(http.StatusOK))
+	Expect(infos[0].Err).To(BeNil())
+
+	_, err = client.Modify(api.NewArchiveAction(1))
+	Expect(err).NotTo(BeNil())
+	Expect(infos).To(HaveLen(2))
+	Expect(infos[1].URL).To(Equal(ts.URL + "/v3/send"))
+	Expect(infos[1].StatusCode).To(Equal(http.StatusServiceUnavailable))
+	Expect(infos[1].Err).To(Equal(err))
+}
+
+func TestAPIErrorUnauthorized(t *testing.T) {
+	RegisterTestingT(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Error-Code", "107")
+		w.Header().Set("X-Error", "Consumer key / access token mismatch.")
+		w.WriteHeader(401)
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "revoked")
+
+	_, err := client.Retrieve(&api.RetrieveOption{})
+	Expect(errors.Is(err, api.ErrUnauthorized)).To(BeTrue())
+
+	_, err = client.Modify(api.NewArchiveAction(1))
+	Expect(errors.Is(err, api.ErrUnauthorized)).To(BeTrue())
+
+	var apiErr *api.APIError
+	Expect(errors.As(err, &apiErr)).To(BeTrue())
+	Expect(apiErr.Code).To(Equal(107))
+
+	Expect(errors.Is(&api.APIError{StatusCode: 503}, api.ErrUnauthorized)).To(BeFalse())
+}
+
+func TestAPIError(t *testing.T) {
+	RegisterTestingT(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Error-Code", "199")
+		w.Header().Set("X-Error", "Pocket server issue")
+		w.WriteHeader(503)
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+
+	_, err := client.Retrieve(&api.RetrieveOption{})
+
+	var apiErr *api.APIError
+	Expect(errors.As(err, &apiErr)).To(BeTrue())
+	Expect(apiErr.StatusCode).To(Equal(503))
+	Expect(apiErr.Code).To(Equal(199))
+	Expect(apiErr.Message).To(Equal("Pocket server issue"))
+
+	_, err = client.Modify(api.NewArchiveAction(1))
+	Expect(errors.As(err, &apiErr)).To(BeTrue())
+
+	_, err = client.Add(&api.AddOption{URL: "https://example.com/"})
+	Expect(errors.As(err, &apiErr)).To(BeTrue())
+}
+
+func TestClientUserAgent(t *testing.T) {
+	RegisterTestingT(t)
+
+	var userAgent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		w.Write([]byte(`{"list":{},"status":1}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+
+	_, err := client.Retrieve(&api.RetrieveOption{})
+	Expect(err).To(BeNil())
+	Expect(userAgent).To(Equal(api.DefaultUserAgent))
+
+	client.UserAgent = "my-app/1.0"
+	_, err = client.Retrieve(&api.RetrieveOption{})
+	Expect(err).To(BeNil())
+	Expect(userAgent).To(Equal("my-app/1.0"))
+}
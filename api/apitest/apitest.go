@@ -0,0 +1,407 @@
+// Package apitest provides an in-memory fake of the Pocket API for testing
+// code which uses the api package.
+package apitest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bvp/go-pocket/api"
+)
+
+// The credentials clients of a Server must use.
+const (
+	ConsumerKey = "apitest-consumer-key"
+	AccessToken = "apitest-access-token"
+)
+
+// Server is a fake Pocket API server keeping items in memory. It supports the
+// add, send and retrieve APIs; deleted items are kept so they can be reported
+// to retrieve requests with a since cursor.
+type Server struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	items  map[int64]*api.Item
+	nextID int64
+	clock  int64
+}
+
+// NewServer starts a Server. It must be closed by the caller.
+func NewServer() *Server {
+	s := &Server{
+		items: map[int64]*api.Item{},
+		clock: time.Now().Unix(),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Client returns a client talking to s.
+func (s *Server) Client() *api.Client {
+	client := api.NewClient(ConsumerKey, AccessToken)
+	client.BaseURL = s.URL + "/v3"
+	return client
+}
+
+// Items returns the items which have not been deleted, in the order they were
+// added.
+func (s *Server) Items() []api.Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := []api.Item{}
+	for _, item := range s.sortedItems() {
+		if item.Status != api.ItemStatusDeleted {
+			items = append(items, *item)
+		}
+	}
+	return items
+}
+
+func (s *Server) sortedItems() []*api.Item {
+	items := make([]*api.Item, 0, len(s.items))
+	for _, item := range s.items {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ItemID < items[j].ItemID })
+	return items
+}
+
+type auth struct {
+	ConsumerKey string `json:"consumer_key"`
+	AccessToken string `json:"access_token"`
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var body json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, 0, err.Error())
+		return
+	}
+
+	var a auth
+	json.Unmarshal(body, &a)
+	if a.ConsumerKey != ConsumerKey {
+		writeError(w, http.StatusForbidden, 152, "Invalid consumer key.")
+		return
+	}
+	if a.AccessToken != AccessToken {
+		writeError(w, http.StatusUnauthorized, 107, "Invalid access token.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res interface{}
+	var err error
+	switch r.URL.Path {
+	case "/v3/add":
+		res, err = s.handleAdd(body)
+	case "/v3/send":
+		res, err = s.handleSend(body)
+	case "/v3/get":
+		res, err = s.handleGet(body)
+	default:
+		writeError(w, http.StatusNotFound, 0, "Not found.")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, 0, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+func writeError(w http.ResponseWriter, status, code int, message string) {
+	w.Header().Set("X-Error-Code", strconv.Itoa(code))
+	w.Header().Set("X-Error", message)
+	w.WriteHeader(status)
+}
+
+func (s *Server) tick() api.Time {
+	s.clock++
+	return api.Time(time.Unix(s.clock, 0))
+}
+
+func (s *Server) handleAdd(body json.RawMessage) (interface{}, error) {
+	var options api.AddOption
+	if err := json.Unmarshal(body, &options); err != nil {
+		return nil, err
+	}
+
+	item := s.add(options.URL, options.Title, options.Tags, options.Time)
+	return map[string]interface{}{
+		"item":   addResult(item),
+		"status": 1,
+	}, nil
+}
+
+func (s *Server) add(rawURL, title, tags string, added int64) *api.Item {
+	s.nextID++
+	now := s.tick()
+	item := &api.Item{
+		ItemID:     s.nextID,
+		ResolvedId: s.nextID,
+		GivenURL:   rawURL,
+		GivenTitle: title,
+		TimeAdded:  now,
+	}
+	if added != 0 {
+		item.TimeAdded = api.Time(time.Unix(added, 0))
+	}
+	item.TimeUpdated = now
+	addTags(item, strings.Split(tags, ","))
+
+	s.items[item.ItemID] = item
+	return item
+}
+
+func addResult(item *api.Item) map[string]interface{} {
+	return map[string]interface{}{
+		"item_id":      strconv.FormatInt(item.ItemID, 10),
+		"normal_url":   item.GivenURL,
+		"resolved_url": item.GivenURL,
+		"title":        item.GivenTitle,
+	}
+}
+
+func addTags(item *api.Item, tags []string) {
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if item.Tags == nil {
+			item.Tags = map[string]map[string]interface{}{}
+		}
+		item.Tags[tag] = map[string]interface{}{
+			"item_id": strconv.FormatInt(item.ItemID, 10),
+			"tag":     tag,
+		}
+	}
+}
+
+func (s *Server) handleSend(body json.RawMessage) (interface{}, error) {
+	var req struct {
+		Actions []*api.Action `json:"actions"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+
+	results := []interface{}{}
+	messages := []interface{}{}
+	for _, action := range req.Actions {
+		result, message := s.perform(action)
+		results = append(results, result)
+		if message == "" {
+			messages = append(messages, nil)
+		} else {
+			messages = append(messages, map[string]interface{}{"message": message})
+		}
+	}
+
+	return map[string]interface{}{
+		"action_results": results,
+		"action_errors":  messages,
+		"status":         1,
+	}, nil
+}
+
+// perform applies action, returning its entry of action_results and an error
+// message when it failed.
+func (s *Server) perform(action *api.Action) (interface{}, string) {
+	switch action.Action {
+	case "add":
+		return addResult(s.add(action.URL, action.Title, action.Tags, action.Time)), ""
+	case "tag_rename", "tag_delete":
+		for _, item := range s.items {
+			if item.Status == api.ItemStatusDeleted {
+				continue
+			}
+			if action.Action == "tag_rename" {
+				if _, ok := item.Tags[action.OldTag]; !ok {
+					continue
+				}
+				delete(item.Tags, action.OldTag)
+				addTags(item, []string{action.NewTag})
+			} else {
+				if _, ok := item.Tags[action.Tag]; !ok {
+					continue
+				}
+				delete(item.Tags, action.Tag)
+			}
+			item.TimeUpdated = s.tick()
+		}
+		return true, ""
+	}
+
+	item, ok := s.items[int64(action.ItemID)]
+	if !ok || item.Status == api.ItemStatusDeleted {
+		return false, "Item not found."
+	}
+
+	now := s.tick()
+	switch action.Action {
+	case "archive":
+		item.Status = api.ItemStatusArchived
+		item.TimeRead = now
+	case "readd":
+		item.Status = api.ItemStatusUnread
+	case "favorite":
+		item.Favorite = 1
+		item.TimeFavorited = now
+	case "unfavorite":
+		item.Favorite = 0
+	case "delete":
+		item.Status = api.ItemStatusDeleted
+	case "tags_add":
+		addTags(item, strings.Split(action.Tags, ","))
+	case "tags_remove":
+		for _, tag := range strings.Split(action.Tags, ",") {
+			delete(item.Tags, strings.TrimSpace(tag))
+		}
+	case "tags_replace":
+		item.Tags = nil
+		addTags(item, strings.Split(action.Tags, ","))
+	case "tags_clear":
+		item.Tags = nil
+	default:
+		return false, "Invalid action."
+	}
+	item.TimeUpdated = now
+
+	return true, ""
+}
+
+func (s *Server) handleGet(body json.RawMessage) (interface{}, error) {
+	var options api.RetrieveOption
+	if err := json.Unmarshal(body, &options); err != nil {
+		return nil, err
+	}
+
+	items := []*api.Item{}
+	for _, item := range s.sortedItems() {
+		if matches(item, &options) {
+			items = append(items, item)
+		}
+	}
+
+	sortItems(items, options.Sort)
+
+	if options.Count > 0 {
+		if options.Offset >= len(items) {
+			items = nil
+		} else {
+			items = items[options.Offset:]
+		}
+		if len(items) > options.Count {
+			items = items[:options.Count]
+		}
+	}
+
+	list := map[string]api.Item{}
+	for i, item := range items {
+		listed := *item
+		listed.SortId = i
+		list[strconv.FormatInt(item.ItemID, 10)] = listed
+	}
+
+	return map[string]interface{}{
+		"list":     list,
+		"status":   1,
+		"complete": 1,
+		"since":    s.clock,
+	}, nil
+}
+
+func matches(item *api.Item, options *api.RetrieveOption) bool {
+	if options.Since != 0 {
+		// Changes since the cursor are reported whatever the item's state,
+		// so that deleted items can be noticed.
+		return item.TimeUpdated.Time().Unix() > options.Since
+	}
+	if item.Status == api.ItemStatusDeleted {
+		return false
+	}
+
+	switch options.State {
+	case "", api.StateUnread:
+		if item.Status != api.ItemStatusUnread {
+			return false
+		}
+	case api.StateArchive:
+		if item.Status != api.ItemStatusArchived {
+			return false
+		}
+	}
+
+	switch options.Favorite {
+	case api.FavoriteFilterFavorited:
+		if item.Favorite != 1 {
+			return false
+		}
+	case api.FavoriteFilterUnfavorited:
+		if item.Favorite != 0 {
+			return false
+		}
+	}
+
+	if options.Tag == "_untagged_" {
+		if len(item.Tags) > 0 {
+			return false
+		}
+	} else if options.Tag != "" {
+		if _, ok := item.Tags[options.Tag]; !ok {
+			return false
+		}
+	}
+
+	if options.Domain != "" && hostname(item.URL()) != options.Domain {
+		return false
+	}
+
+	if options.Search != "" {
+		search := strings.ToLower(options.Search)
+		if !strings.Contains(strings.ToLower(item.Title()), search) &&
+			!strings.Contains(strings.ToLower(item.URL()), search) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func hostname(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+func sortItems(items []*api.Item, order api.Sort) {
+	var less func(a, b *api.Item) bool
+	switch order {
+	case api.SortOldest:
+		less = func(a, b *api.Item) bool { return a.TimeAdded.Time().Before(b.TimeAdded.Time()) }
+	case api.SortTitle:
+		less = func(a, b *api.Item) bool { return a.Title() < b.Title() }
+	case api.SortSite:
+		less = func(a, b *api.Item) bool { return a.URL() < b.URL() }
+	default:
+		less = func(a, b *api.Item) bool { return a.TimeAdded.Time().After(b.TimeAdded.Time()) }
+	}
+	sort.SliceStable(items, func(i, j int) bool { return less(items[i], items[j]) })
+}
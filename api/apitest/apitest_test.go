@@ -0,0 +1,106 @@
+package apitest_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bvp/go-pocket/api"
+	"github.com/bvp/go-pocket/api/apitest"
+	. "github.com/onsi/gomega"
+)
+
+func TestAddThenRetrieve(t *testing.T) {
+	RegisterTestingT(t)
+
+	s := apitest.NewServer()
+	defer s.Close()
+	client := s.Client()
+
+	res, err := client.Add(&api.AddOption{URL: "https://example.com/1", Title: "First", Tags: "go,cli"})
+	Expect(err).To(BeNil())
+	Expect(res.ItemID).To(Equal(int64(1)))
+
+	results, err := client.AddBatch([]*api.AddOption{
+		{URL: "https://example.org/2", Title: "Second", Time: 1577836800},
+	})
+	Expect(err).To(BeNil())
+	Expect(results[0].ItemID).To(Equal(int64(2)))
+
+	items, err := client.RetrieveAll(&api.RetrieveOption{Sort: api.SortOldest})
+	Expect(err).To(BeNil())
+	Expect(items).To(HaveLen(2))
+	Expect(items[0].Title()).To(Equal("Second"))
+	Expect(items[1].TagNames()).To(Equal([]string{"cli", "go"}))
+
+	tagged, err := client.Retrieve(&api.RetrieveOption{Tag: "go"})
+	Expect(err).To(BeNil())
+	Expect(tagged.List).To(HaveLen(1))
+	Expect(tagged.List).To(HaveKey("1"))
+
+	byDomain, err := client.Retrieve(&api.RetrieveOption{Domain: "example.org"})
+	Expect(err).To(BeNil())
+	Expect(byDomain.List).To(HaveKey("2"))
+	Expect(byDomain.List).To(HaveLen(1))
+}
+
+func TestModifyThenRetrieve(t *testing.T) {
+	RegisterTestingT(t)
+
+	s := apitest.NewServer()
+	defer s.Close()
+	client := s.Client()
+
+	_, err := client.AddBatch([]*api.AddOption{
+		{URL: "https://example.com/1"},
+		{URL: "https://example.com/2"},
+		{URL: "https://example.com/3"},
+	})
+	Expect(err).To(BeNil())
+
+	before, err := client.Retrieve(&api.RetrieveOption{State: api.StateAll})
+	Expect(err).To(BeNil())
+
+	res, err := client.Modify(
+		api.NewArchiveAction(1),
+		api.NewFavoriteAction(2),
+		api.NewDeleteAction(3),
+		api.NewArchiveAction(42),
+	)
+	Expect(err).To(BeNil())
+	Expect(res.ActionResults).To(Equal([]bool{true, true, true, false}))
+	Expect(res.ActionErrors[3]).To(Equal("Item not found."))
+
+	archived, err := client.Retrieve(&api.RetrieveOption{State: api.StateArchive})
+	Expect(err).To(BeNil())
+	Expect(archived.List).To(HaveLen(1))
+	Expect(archived.List).To(HaveKey("1"))
+
+	favorited, err := client.Retrieve(&api.RetrieveOption{Favorite: api.FavoriteFilterFavorited})
+	Expect(err).To(BeNil())
+	Expect(favorited.List).To(HaveLen(1))
+	Expect(favorited.List).To(HaveKey("2"))
+
+	changed, err := client.Retrieve(&api.RetrieveOption{Since: before.Since})
+	Expect(err).To(BeNil())
+	Expect(changed.List).To(HaveLen(3))
+	Expect(changed.List["3"].Status).To(Equal(api.ItemStatus(api.ItemStatusDeleted)))
+
+	Expect(s.Items()).To(HaveLen(2))
+}
+
+func TestUnauthorized(t *testing.T) {
+	RegisterTestingT(t)
+
+	s := apitest.NewServer()
+	defer s.Close()
+
+	client := api.NewClient(apitest.ConsumerKey, "wrong")
+	client.BaseURL = s.URL + "/v3"
+
+	_, err := client.Retrieve(nil)
+
+	var apiErr *api.APIError
+	Expect(errors.As(err, &apiErr)).To(BeTrue())
+	Expect(apiErr.StatusCode).To(Equal(401))
+	Expect(apiErr.Code).To(Equal(107))
+}
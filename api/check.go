@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// DefaultCheckTimeout is how long CheckURL waits for a site to respond.
+const DefaultCheckTimeout = 10 * time.Second
+
+// CheckURL requests rawURL from its site, following redirects, to find dead
+// links among saved items. It returns the URL it ended up at and the status
+// of the response, like 404 or 410 for a page which is gone. The site must
+// respond within DefaultCheckTimeout.
+func (c *Client) CheckURL(rawURL string) (string, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultCheckTimeout)
+	defer cancel()
+
+	return c.CheckURLContext(ctx, rawURL, true)
+}
+
+// CheckURLContext is like CheckURL, but the request is bound to ctx, which
+// sets its timeout. Unless followRedirects is true, a redirect is returned as
+// it is, with the URL it points to.
+//
+// The request is made with the client's http.Client. A HEAD request is tried
+// first, then a GET if the site does not allow HEAD.
+func (c *Client) CheckURLContext(ctx context.Context, rawURL string, followRedirects bool) (string, int, error) {
+	if err := validateURL(rawURL); err != nil {
+		return "", 0, err
+	}
+
+	hc := c.httpClient
+	if hc == nil {
+		hc = DefaultClient
+	}
+	if !followRedirects {
+		noRedirects := *hc
+		noRedirects.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+		hc = &noRedirects
+	}
+
+	resp, err := c.check(ctx, hc, "HEAD", rawURL)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp, err = c.check(ctx, hc, "GET", rawURL)
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	resolved := resp.Request.URL.String()
+	if location, err := resp.Location(); err == nil {
+		resolved = location.String()
+	}
+	return resolved, resp.StatusCode, nil
+}
+
+// check sends a request for rawURL and returns the response, with its body
+// already closed.
+func (c *Client) check(ctx context.Context, hc *http.Client, method, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
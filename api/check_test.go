@@ -0,0 +1,98 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bvp/go-pocket/api"
+	. "github.com/onsi/gomega"
+)
+
+func checkServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/get-only", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestCheckURL(t *testing.T) {
+	RegisterTestingT(t)
+
+	ts := checkServer()
+	defer ts.Close()
+	client := api.NewClient("consumer", "token")
+
+	resolved, status, err := client.CheckURL(ts.URL + "/ok")
+	Expect(err).To(BeNil())
+	Expect(resolved).To(Equal(ts.URL + "/ok"))
+	Expect(status).To(Equal(http.StatusOK))
+
+	resolved, status, err = client.CheckURL(ts.URL + "/missing")
+	Expect(err).To(BeNil())
+	Expect(resolved).To(Equal(ts.URL + "/missing"))
+	Expect(status).To(Equal(http.StatusNotFound))
+
+	_, status, err = client.CheckURL(ts.URL + "/gone")
+	Expect(err).To(BeNil())
+	Expect(status).To(Equal(http.StatusGone))
+
+	_, status, err = client.CheckURL(ts.URL + "/get-only")
+	Expect(err).To(BeNil())
+	Expect(status).To(Equal(http.StatusOK))
+
+	_, _, err = client.CheckURL("example.com/1")
+	Expect(errors.Is(err, api.ErrInvalidURL)).To(BeTrue())
+}
+
+func TestCheckURLRedirects(t *testing.T) {
+	RegisterTestingT(t)
+
+	ts := checkServer()
+	defer ts.Close()
+	client := api.NewClient("consumer", "token")
+
+	resolved, status, err := client.CheckURL(ts.URL + "/moved")
+	Expect(err).To(BeNil())
+	Expect(resolved).To(Equal(ts.URL + "/ok"))
+	Expect(status).To(Equal(http.StatusOK))
+
+	resolved, status, err = client.CheckURLContext(context.Background(), ts.URL+"/moved", false)
+	Expect(err).To(BeNil())
+	Expect(resolved).To(Equal(ts.URL + "/ok"))
+	Expect(status).To(Equal(http.StatusMovedPermanently))
+}
+
+func TestCheckURLTimeout(t *testing.T) {
+	RegisterTestingT(t)
+
+	ts := checkServer()
+	defer ts.Close()
+	client := api.NewClient("consumer", "token")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, status, err := client.CheckURLContext(ctx, ts.URL+"/slow", true)
+	Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+	Expect(status).To(BeZero())
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// FindDuplicates retrieves every item, archived ones included, and returns
+// those saved more than once, keyed by their normalized URL. See
+// ItemList.Duplicates for how URLs are compared.
+func (c *Client) FindDuplicates() (map[string][]Item, error) {
+	items, err := c.RetrieveAll(&RetrieveOption{State: StateAll})
+	if err != nil {
+		return nil, err
+	}
+
+	return ItemList(items).Duplicates(), nil
+}
+
+// Duplicates groups the items of l sharing a URL, once the host is lowercased
+// and trailing slashes and tracking parameters are removed, keyed by that
+// normalized URL. URLs of a single item are left out. Each group is ordered by
+// the time its items were added, oldest first.
+func (l ItemList) Duplicates() map[string][]Item {
+	groups := map[string][]Item{}
+	for _, item := range l {
+		key := normalizeURL(item)
+		groups[key] = append(groups[key], item)
+	}
+
+	for key, items := range groups {
+		if len(items) < 2 {
+			delete(groups, key)
+			continue
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].TimeAdded.Time().Before(items[j].TimeAdded.Time())
+		})
+	}
+	return groups
+}
+
+func normalizeURL(item Item) string {
+	rawURL := item.CleanURL()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	u.Host = strings.ToLower(u.Host)
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+	return u.String()
+}
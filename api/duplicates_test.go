@@ -0,0 +1,49 @@
+package api_test
+
+import (
+	"testing"
+
+	"github.com/bvp/go-pocket/api"
+	"github.com/bvp/go-pocket/api/apitest"
+	. "github.com/onsi/gomega"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	RegisterTestingT(t)
+
+	server := apitest.NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	_, err := client.ModifyBatch(
+		addAction(&api.AddOption{URL: "https://example.com/article?id=1&utm_source=feed", Time: 1577836900}),
+		addAction(&api.AddOption{URL: "https://EXAMPLE.com/article/?id=1", Time: 1577836800}),
+		addAction(&api.AddOption{URL: "https://example.com/article?id=2"}),
+		addAction(&api.AddOption{URL: "https://example.com/other"}),
+		api.NewArchiveAction(1),
+	)
+	Expect(err).To(BeNil())
+
+	duplicates, err := client.FindDuplicates()
+	Expect(err).To(BeNil())
+	Expect(duplicates).To(HaveLen(1))
+
+	// The oldest item comes first, whether archived or not.
+	items := duplicates["https://example.com/article?id=1"]
+	Expect(itemIDs(items)).To(Equal([]int64{2, 1}))
+}
+
+func TestItemListDuplicates(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(testItemList().Duplicates()).To(BeEmpty())
+
+	items := api.ItemList{
+		{ItemID: 1, GivenURL: "https://example.com/a/?fbclid=x#top"},
+		{ItemID: 2, ResolvedURL: "https://example.com/a#top"},
+		{ItemID: 3, GivenURL: "https://example.com/a?page=2"},
+	}
+	Expect(items.Duplicates()).To(Equal(map[string][]api.Item{
+		"https://example.com/a#top": {items[0], items[1]},
+	}))
+}
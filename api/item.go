@@ -0,0 +1,405 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ItemStatus int
+
+const (
+	ItemStatusUnread   ItemStatus = 0
+	ItemStatusArchived            = 1
+	ItemStatusDeleted             = 2
+)
+
+type ItemMediaAttachment int
+
+const (
+	ItemMediaAttachmentNoMedia  ItemMediaAttachment = 0
+	ItemMediaAttachmentHasMedia                     = 1
+	ItemMediaAttachmentIsMedia                      = 2
+)
+
+// Present reports whether the item has or is an image or video.
+func (a ItemMediaAttachment) Present() bool {
+	return a == ItemMediaAttachmentHasMedia || a == ItemMediaAttachmentIsMedia
+}
+
+// IsMedia reports whether the item itself is an image or video.
+func (a ItemMediaAttachment) IsMedia() bool {
+	return a == ItemMediaAttachmentIsMedia
+}
+
+type Item struct {
+	ItemID        int64      `json:"item_id,string"`
+	ResolvedId    int64      `json:"resolved_id,string"`
+	GivenURL      string     `json:"given_url"`
+	ResolvedURL   string     `json:"resolved_url"`
+	GivenTitle    string     `json:"given_title"`
+	ResolvedTitle string     `json:"resolved_title"`
+	Favorite      int        `json:",string"`
+	Status        ItemStatus `json:",string"`
+	Excerpt       string
+	IsArticle     int                 `json:"is_article,string"`
+	HasImage      ItemMediaAttachment `json:"has_image,string"`
+	HasVideo      ItemMediaAttachment `json:"has_video,string"`
+	WordCount     int                 `json:"word_count,string"`
+
+	// Fields for detailed response
+	Tags    map[string]map[string]interface{}
+	Authors map[string]map[string]interface{}
+	Images  map[string]map[string]interface{}
+	Videos  map[string]map[string]interface{}
+	// Image is the item's top image, as decoded by TopImage.
+	Image map[string]interface{} `json:"image"`
+	// DomainMeta describes the item's site, as decoded by DomainMetadata.
+	DomainMeta map[string]interface{} `json:"domain_metadata"`
+
+	// Fields that are not documented but exist
+	SortId        int  `json:"sort_id"`
+	TimeAdded     Time `json:"time_added"`
+	TimeUpdated   Time `json:"time_updated"`
+	TimeRead      Time `json:"time_read"`
+	TimeFavorited Time `json:"time_favorited"`
+}
+
+// Time is a timestamp as Pocket reports it, in unix seconds. Pocket uses "0"
+// for timestamps that are not set, which decodes to the zero time.
+type Time time.Time
+
+func (t *Time) UnmarshalJSON(b []byte) error {
+	s := string(bytes.Trim(b, `"`))
+	if s == "" || s == "0" || s == "null" {
+		*t = Time{}
+		return nil
+	}
+
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	*t = Time(time.Unix(i, 0))
+
+	return nil
+}
+
+// MarshalJSON encodes t in unix seconds, the same way Pocket does.
+func (t Time) MarshalJSON() ([]byte, error) {
+	var i int64
+	if !t.Time().IsZero() {
+		i = t.Time().Unix()
+	}
+
+	return []byte(strconv.Quote(strconv.FormatInt(i, 10))), nil
+}
+
+// Time returns t as a time.Time.
+func (t Time) Time() time.Time {
+	return time.Time(t)
+}
+
+// URL returns ResolvedURL or GivenURL. The resolved URL is preferred since the
+// given one is often a shortener or redirect to it.
+func (item Item) URL() string {
+	url := item.ResolvedURL
+	if url == "" {
+		url = item.GivenURL
+	}
+	return url
+}
+
+// Title returns ResolvedTitle or GivenTitle
+func (item Item) Title() string {
+	title := item.ResolvedTitle
+	if title == "" {
+		title = item.GivenTitle
+	}
+	return title
+}
+
+// trackingParams are the query parameters CleanURL removes, besides those
+// starting with utm_.
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"msclkid": true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+}
+
+// CleanURL returns URL without the query parameters used for tracking, like
+// utm_source or fbclid. The other parameters are kept as they were, in order.
+func (item Item) CleanURL() string {
+	rawURL := item.URL()
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return rawURL
+	}
+
+	kept := []string{}
+	for _, param := range strings.Split(u.RawQuery, "&") {
+		name := param
+		if i := strings.IndexByte(param, '='); i >= 0 {
+			name = param[:i]
+		}
+		if name, err := url.QueryUnescape(name); err == nil {
+			name = strings.ToLower(name)
+			if strings.HasPrefix(name, "utm_") || trackingParams[name] {
+				continue
+			}
+		}
+		kept = append(kept, param)
+	}
+
+	u.RawQuery = strings.Join(kept, "&")
+	return u.String()
+}
+
+// Article reports whether Pocket considers the item an article.
+func (item Item) Article() bool {
+	return item.IsArticle == 1
+}
+
+// DefaultWordsPerMinute is the reading speed EstimatedReadingTime assumes when
+// none is given.
+const DefaultWordsPerMinute = 200
+
+// EstimatedReadingTime returns how long the item takes to read at wpm words per
+// minute, based on its WordCount. A wpm of zero or less uses
+// DefaultWordsPerMinute.
+func (item Item) EstimatedReadingTime(wpm int) time.Duration {
+	if wpm <= 0 {
+		wpm = DefaultWordsPerMinute
+	}
+
+	return time.Duration(item.WordCount) * time.Minute / time.Duration(wpm)
+}
+
+// TagNames returns the names of the item's tags in sorted order. Tags are only
+// included in responses with DetailTypeComplete.
+func (item Item) TagNames() []string {
+	names := make([]string, 0, len(item.Tags))
+	for name := range item.Tags {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Author is an author of an item, as included in responses with
+// DetailTypeComplete.
+type Author struct {
+	ID   int64  `json:"author_id,string"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// AuthorList returns the item's authors ordered by id.
+func (item Item) AuthorList() []Author {
+	authors := make([]Author, 0, len(item.Authors))
+	for _, raw := range item.Authors {
+		var author Author
+		if err := remarshal(raw, &author); err != nil {
+			continue
+		}
+		authors = append(authors, author)
+	}
+
+	sort.Slice(authors, func(i, j int) bool { return authors[i].ID < authors[j].ID })
+
+	return authors
+}
+
+// Image is an image of an item, as included in responses with
+// DetailTypeComplete. Width and Height are zero when Pocket does not know them.
+type Image struct {
+	ImageID int64  `json:"image_id,string"`
+	Src     string `json:"src"`
+	Width   int    `json:"width,string"`
+	Height  int    `json:"height,string"`
+	Credit  string `json:"credit"`
+	Caption string `json:"caption"`
+}
+
+// DomainMetadata describes the site an item is on, as included in responses
+// with DetailTypeComplete.
+type DomainMetadata struct {
+	Name          string `json:"name"`
+	Logo          string `json:"logo"`
+	GreyscaleLogo string `json:"greyscale_logo"`
+}
+
+// DomainMetadata returns what Pocket knows about the item's site, or nil if
+// it has nothing.
+func (item Item) DomainMetadata() *DomainMetadata {
+	if len(item.DomainMeta) == 0 {
+		return nil
+	}
+
+	meta := &DomainMetadata{}
+	if err := remarshal(item.DomainMeta, meta); err != nil || *meta == (DomainMetadata{}) {
+		return nil
+	}
+	return meta
+}
+
+// TopImage returns the item's top image, or nil if it has none.
+func (item Item) TopImage() *Image {
+	if len(item.Image) == 0 {
+		return nil
+	}
+
+	image := &Image{}
+	if err := remarshal(item.Image, image); err != nil || image.Src == "" {
+		return nil
+	}
+	return image
+}
+
+// ImageList returns the item's images ordered by id.
+func (item Item) ImageList() []Image {
+	images := make([]Image, 0, len(item.Images))
+	for _, raw := range item.Images {
+		var image Image
+		if err := remarshal(raw, &image); err != nil {
+			continue
+		}
+		images = append(images, image)
+	}
+
+	sort.Slice(images, func(i, j int) bool { return images[i].ImageID < images[j].ImageID })
+
+	return images
+}
+
+// Video is a video embedded in an item, as included in responses with
+// DetailTypeComplete. Type is Pocket's code for the video's host, and VID the
+// video's id there.
+type Video struct {
+	VideoID int64  `json:"video_id,string"`
+	Src     string `json:"src"`
+	Width   int    `json:"width,string"`
+	Height  int    `json:"height,string"`
+	Type    int    `json:"type,string"`
+	VID     string `json:"vid"`
+	// Length is the video's duration in seconds, or zero when unknown.
+	Length int `json:"length,string"`
+}
+
+// VideoList returns the item's videos ordered by id.
+func (item Item) VideoList() []Video {
+	videos := make([]Video, 0, len(item.Videos))
+	for _, raw := range item.Videos {
+		var video Video
+		if err := remarshal(raw, &video); err != nil {
+			continue
+		}
+		videos = append(videos, video)
+	}
+
+	sort.Slice(videos, func(i, j int) bool { return videos[i].VideoID < videos[j].VideoID })
+
+	return videos
+}
+
+// UnmarshalJSON decodes an item. Pocket sends an empty array instead of an
+// empty object for the detail fields of items which have none, so those are
+// accepted in either form.
+func (item *Item) UnmarshalJSON(b []byte) error {
+	type plainItem Item
+	aux := struct {
+		*plainItem
+		Tags    json.RawMessage `json:"tags"`
+		Authors json.RawMessage `json:"authors"`
+		Images  json.RawMessage `json:"images"`
+		Videos  json.RawMessage `json:"videos"`
+		Image   json.RawMessage `json:"image"`
+		Domain  json.RawMessage `json:"domain_metadata"`
+	}{plainItem: (*plainItem)(item)}
+
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+
+	for _, object := range []struct {
+		raw json.RawMessage
+		dst *map[string]interface{}
+	}{
+		{aux.Image, &item.Image},
+		{aux.Domain, &item.DomainMeta},
+	} {
+		if raw := bytes.TrimSpace(object.raw); len(raw) > 0 && raw[0] == '{' {
+			if err := json.Unmarshal(raw, object.dst); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, detail := range []struct {
+		raw json.RawMessage
+		dst *map[string]map[string]interface{}
+	}{
+		{aux.Tags, &item.Tags},
+		{aux.Authors, &item.Authors},
+		{aux.Images, &item.Images},
+		{aux.Videos, &item.Videos},
+	} {
+		// Fields missing from b are left as they are.
+		if len(detail.raw) == 0 {
+			continue
+		}
+		if err := decodeDetail(detail.raw, detail.dst); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decodeDetail decodes a detail field given either as an object keyed by id
+// or as an array, which is keyed by position.
+func decodeDetail(raw json.RawMessage, dst *map[string]map[string]interface{}) error {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		*dst = nil
+		return nil
+	}
+	if raw[0] != '[' {
+		return json.Unmarshal(raw, dst)
+	}
+
+	var list []map[string]interface{}
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return err
+	}
+
+	*dst = nil
+	for i, v := range list {
+		if *dst == nil {
+			*dst = map[string]map[string]interface{}{}
+		}
+		(*dst)[strconv.Itoa(i)] = v
+	}
+
+	return nil
+}
+
+// remarshal decodes the generic value v into the typed value dst.
+func remarshal(v, dst interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, dst)
+}
@@ -0,0 +1,280 @@
+package api_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bvp/go-pocket/api"
+	. "github.com/onsi/gomega"
+)
+
+func TestItemTimes(t *testing.T) {
+	RegisterTestingT(t)
+
+	var item api.Item
+	err := json.Unmarshal([]byte(`{
+		"item_id":"1",
+		"time_added":"1577836800",
+		"time_updated":"1577836900",
+		"time_read":"0",
+		"time_favorited":""
+	}`), &item)
+
+	Expect(err).To(BeNil())
+	Expect(item.TimeAdded.Time()).To(Equal(time.Unix(1577836800, 0)))
+	Expect(item.TimeUpdated.Time()).To(Equal(time.Unix(1577836900, 0)))
+	Expect(item.TimeRead.Time().IsZero()).To(BeTrue())
+	Expect(item.TimeFavorited.Time().IsZero()).To(BeTrue())
+
+	item = api.Item{}
+	err = json.Unmarshal([]byte(`{"item_id":"1"}`), &item)
+
+	Expect(err).To(BeNil())
+	Expect(item.TimeAdded.Time().IsZero()).To(BeTrue())
+}
+
+func TestItemTagNames(t *testing.T) {
+	RegisterTestingT(t)
+
+	var item api.Item
+	err := json.Unmarshal([]byte(`{
+		"item_id":"1",
+		"tags":{
+			"reading":{"item_id":"1","tag":"reading"},
+			"go":{"item_id":"1","tag":"go"}
+		}
+	}`), &item)
+
+	Expect(err).To(BeNil())
+	Expect(item.TagNames()).To(Equal([]string{"go", "reading"}))
+
+	Expect(api.Item{}.TagNames()).To(Equal([]string{}))
+}
+
+func TestItemAuthorList(t *testing.T) {
+	RegisterTestingT(t)
+
+	var item api.Item
+	err := json.Unmarshal([]byte(`{
+		"item_id":"1",
+		"authors":{
+			"52":{"item_id":"1","author_id":"52","name":"Rob Pike","url":"https://example.com/r"},
+			"7":{"item_id":"1","author_id":"7","name":"Ken Thompson","url":""}
+		}
+	}`), &item)
+
+	Expect(err).To(BeNil())
+	Expect(item.AuthorList()).To(Equal([]api.Author{
+		{ID: 7, Name: "Ken Thompson"},
+		{ID: 52, Name: "Rob Pike", URL: "https://example.com/r"},
+	}))
+}
+
+func TestItemAuthorListEmpty(t *testing.T) {
+	RegisterTestingT(t)
+
+	for _, data := range []string{
+		`{"item_id":"1"}`,
+		`{"item_id":"1","authors":[]}`,
+		`{"item_id":"1","authors":{}}`,
+	} {
+		var item api.Item
+		err := json.Unmarshal([]byte(data), &item)
+
+		Expect(err).To(BeNil())
+		Expect(item.ItemID).To(Equal(int64(1)))
+		Expect(item.AuthorList()).To(BeEmpty())
+	}
+}
+
+func TestItemEstimatedReadingTime(t *testing.T) {
+	RegisterTestingT(t)
+
+	var item api.Item
+	err := json.Unmarshal([]byte(`{"item_id":"1","word_count":"1000"}`), &item)
+
+	Expect(err).To(BeNil())
+	Expect(item.WordCount).To(Equal(1000))
+	Expect(item.EstimatedReadingTime(250)).To(Equal(4 * time.Minute))
+	Expect(item.EstimatedReadingTime(0)).To(Equal(5 * time.Minute))
+	Expect(item.EstimatedReadingTime(-1)).To(Equal(5 * time.Minute))
+
+	Expect(api.Item{}.EstimatedReadingTime(200)).To(Equal(time.Duration(0)))
+}
+
+func TestItemMediaFlags(t *testing.T) {
+	RegisterTestingT(t)
+
+	var item api.Item
+	err := json.Unmarshal([]byte(`{
+		"item_id":"1",
+		"excerpt":"An excerpt",
+		"is_article":"1",
+		"has_image":"2",
+		"has_video":"0"
+	}`), &item)
+
+	Expect(err).To(BeNil())
+	Expect(item.Excerpt).To(Equal("An excerpt"))
+	Expect(item.Article()).To(BeTrue())
+	Expect(item.HasImage.Present()).To(BeTrue())
+	Expect(item.HasImage.IsMedia()).To(BeTrue())
+	Expect(item.HasVideo.Present()).To(BeFalse())
+	Expect(item.HasVideo.IsMedia()).To(BeFalse())
+
+	item = api.Item{}
+	err = json.Unmarshal([]byte(`{"item_id":"1","is_article":"0","has_image":"1"}`), &item)
+
+	Expect(err).To(BeNil())
+	Expect(item.Article()).To(BeFalse())
+	Expect(item.HasImage.Present()).To(BeTrue())
+	Expect(item.HasImage.IsMedia()).To(BeFalse())
+}
+
+func TestItemURL(t *testing.T) {
+	RegisterTestingT(t)
+
+	item := api.Item{
+		GivenURL:    "https://t.co/abc",
+		ResolvedURL: "https://example.com/article",
+	}
+	Expect(item.URL()).To(Equal("https://example.com/article"))
+
+	item = api.Item{GivenURL: "https://t.co/abc"}
+	Expect(item.URL()).To(Equal("https://t.co/abc"))
+}
+
+func TestItemCleanURL(t *testing.T) {
+	RegisterTestingT(t)
+
+	item := api.Item{ResolvedURL: "https://example.com/article?id=42&utm_source=feed&UTM_Medium=rss&page=2&fbclid=abc&gclid=def#comments"}
+	Expect(item.CleanURL()).To(Equal("https://example.com/article?id=42&page=2#comments"))
+	Expect(item.URL()).To(ContainSubstring("utm_source"))
+
+	item = api.Item{ResolvedURL: "https://example.com/article?utm_campaign=x"}
+	Expect(item.CleanURL()).To(Equal("https://example.com/article"))
+
+	item = api.Item{ResolvedURL: "https://example.com/search?q=a+b&sort=new"}
+	Expect(item.CleanURL()).To(Equal("https://example.com/search?q=a+b&sort=new"))
+}
+
+func TestTimeMarshalJSON(t *testing.T) {
+	RegisterTestingT(t)
+
+	b, err := json.Marshal(api.Time(time.Unix(1577836800, 0)))
+	Expect(err).To(BeNil())
+	Expect(string(b)).To(Equal(`"1577836800"`))
+
+	b, err = json.Marshal(api.Time{})
+	Expect(err).To(BeNil())
+	Expect(string(b)).To(Equal(`"0"`))
+}
+
+func TestItemJSONRoundTrip(t *testing.T) {
+	RegisterTestingT(t)
+
+	var item api.Item
+	err := json.Unmarshal([]byte(`{
+		"item_id":"1",
+		"given_url":"https://example.com/1",
+		"tags":{"go":{"item_id":"1","tag":"go"}},
+		"time_added":"1577836800"
+	}`), &item)
+	Expect(err).To(BeNil())
+
+	b, err := json.Marshal(item)
+	Expect(err).To(BeNil())
+
+	var decoded api.Item
+	Expect(json.Unmarshal(b, &decoded)).To(Succeed())
+	Expect(decoded).To(Equal(item))
+	Expect(decoded.TagNames()).To(Equal([]string{"go"}))
+}
+
+func TestItemImages(t *testing.T) {
+	RegisterTestingT(t)
+
+	var item api.Item
+	err := json.Unmarshal([]byte(`{
+		"item_id":"1",
+		"has_image":"1",
+		"image":{"item_id":"1","src":"https://example.com/top.jpg","width":"640","height":"480"},
+		"images":{
+			"2":{"item_id":"1","image_id":"2","src":"https://example.com/2.png","width":"0","height":"0","credit":"","caption":"Second"},
+			"1":{"item_id":"1","image_id":"1","src":"https://example.com/top.jpg","width":"640","height":"480","credit":"Photo: Gopher","caption":""}
+		}
+	}`), &item)
+
+	Expect(err).To(BeNil())
+	Expect(item.TopImage()).To(Equal(&api.Image{Src: "https://example.com/top.jpg", Width: 640, Height: 480}))
+	Expect(item.ImageList()).To(Equal([]api.Image{
+		{ImageID: 1, Src: "https://example.com/top.jpg", Width: 640, Height: 480, Credit: "Photo: Gopher"},
+		{ImageID: 2, Src: "https://example.com/2.png", Caption: "Second"},
+	}))
+}
+
+func TestItemImagesEmpty(t *testing.T) {
+	RegisterTestingT(t)
+
+	var item api.Item
+	err := json.Unmarshal([]byte(`{"item_id":"1","has_image":"0","image":[],"images":[]}`), &item)
+
+	Expect(err).To(BeNil())
+	Expect(item.TopImage()).To(BeNil())
+	Expect(item.ImageList()).To(BeEmpty())
+	Expect(api.Item{}.TopImage()).To(BeNil())
+}
+
+func TestItemDomainMetadata(t *testing.T) {
+	RegisterTestingT(t)
+
+	var item api.Item
+	err := json.Unmarshal([]byte(`{"item_id":"1","domain_metadata":{
+		"name":"Example","logo":"https://logo.example.com/logo.png","greyscale_logo":"https://logo.example.com/grey.png"
+	}}`), &item)
+
+	Expect(err).To(BeNil())
+	Expect(item.DomainMetadata()).To(Equal(&api.DomainMetadata{
+		Name:          "Example",
+		Logo:          "https://logo.example.com/logo.png",
+		GreyscaleLogo: "https://logo.example.com/grey.png",
+	}))
+
+	b, err := json.Marshal(item)
+	Expect(err).To(BeNil())
+	var decoded api.Item
+	Expect(json.Unmarshal(b, &decoded)).To(Succeed())
+	Expect(decoded.DomainMetadata()).To(Equal(item.DomainMetadata()))
+}
+
+func TestItemDomainMetadataMissing(t *testing.T) {
+	RegisterTestingT(t)
+
+	var item api.Item
+	Expect(json.Unmarshal([]byte(`{"item_id":"1"}`), &item)).To(Succeed())
+	Expect(item.DomainMetadata()).To(BeNil())
+
+	Expect(json.Unmarshal([]byte(`{"item_id":"1","domain_metadata":[]}`), &item)).To(Succeed())
+	Expect(item.DomainMetadata()).To(BeNil())
+}
+
+func TestItemVideoList(t *testing.T) {
+	RegisterTestingT(t)
+
+	var item api.Item
+	err := json.Unmarshal([]byte(`{
+		"item_id":"1",
+		"has_video":"1",
+		"videos":{
+			"1":{"item_id":"1","video_id":"1","src":"https://www.youtube.com/embed/abc","width":"420","height":"315","type":"1","vid":"abc","length":"212"}
+		}
+	}`), &item)
+
+	Expect(err).To(BeNil())
+	Expect(item.VideoList()).To(Equal([]api.Video{
+		{VideoID: 1, Src: "https://www.youtube.com/embed/abc", Width: 420, Height: 315, Type: 1, VID: "abc", Length: 212},
+	}))
+
+	Expect(api.Item{}.VideoList()).To(BeEmpty())
+}
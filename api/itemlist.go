@@ -0,0 +1,81 @@
+package api
+
+import (
+	"net/url"
+	"strings"
+)
+
+// ItemList is a list of items which can be filtered client-side, combining
+// criteria the retrieve API cannot. Each filter returns a new list, so they can
+// be chained:
+//
+//	api.ItemList(items).WithTag("go").Favorited()
+type ItemList []Item
+
+// Filter returns the items for which keep returns true.
+func (l ItemList) Filter(keep func(Item) bool) ItemList {
+	filtered := ItemList{}
+	for _, item := range l {
+		if keep(item) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// WithTag returns the items tagged with tag. Tags are only present in
+// complete details.
+func (l ItemList) WithTag(tag string) ItemList {
+	return l.Filter(func(item Item) bool {
+		_, ok := item.Tags[tag]
+		return ok
+	})
+}
+
+// Untagged returns the items without any tags. Tags are only present in
+// complete details.
+func (l ItemList) Untagged() ItemList {
+	return l.Filter(func(item Item) bool {
+		return len(item.Tags) == 0
+	})
+}
+
+// WithDomain returns the items whose URL is on domain. Case and a leading
+// "www." are ignored.
+func (l ItemList) WithDomain(domain string) ItemList {
+	return l.WithAnyDomain(domain)
+}
+
+// WithAnyDomain returns the items whose URL is on any of domains, compared as
+// by WithDomain.
+func (l ItemList) WithAnyDomain(domains ...string) ItemList {
+	want := map[string]bool{}
+	for _, domain := range domains {
+		want[normalizeDomain(domain)] = true
+	}
+
+	return l.Filter(func(item Item) bool {
+		u, err := url.Parse(item.URL())
+		return err == nil && want[normalizeDomain(u.Hostname())]
+	})
+}
+
+func normalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	return strings.TrimPrefix(domain, "www.")
+}
+
+// Favorited returns the favorited items.
+func (l ItemList) Favorited() ItemList {
+	return l.Filter(func(item Item) bool {
+		return item.Favorite == 1
+	})
+}
+
+// WithMinWords returns the items of more than n words, according to their
+// WordCount. Items whose word count is unknown are left out.
+func (l ItemList) WithMinWords(n int) ItemList {
+	return l.Filter(func(item Item) bool {
+		return item.WordCount > 0 && item.WordCount > n
+	})
+}
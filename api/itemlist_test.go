@@ -0,0 +1,93 @@
+package api_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bvp/go-pocket/api"
+	. "github.com/onsi/gomega"
+)
+
+func testItemList() api.ItemList {
+	var items []api.Item
+	err := json.Unmarshal([]byte(`[
+		{"item_id":"1","given_url":"https://example.com/1","favorite":"1","tags":{"go":{"tag":"go"}}},
+		{"item_id":"2","given_url":"https://Example.com/2","favorite":"0","tags":{"go":{"tag":"go"},"cli":{"tag":"cli"}}},
+		{"item_id":"3","resolved_url":"https://example.org/3","favorite":"1","tags":[]}
+	]`), &items)
+	Expect(err).To(BeNil())
+	return api.ItemList(items)
+}
+
+func itemIDs(items api.ItemList) []int64 {
+	ids := []int64{}
+	for _, item := range items {
+		ids = append(ids, item.ItemID)
+	}
+	return ids
+}
+
+func TestItemListWithTag(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(itemIDs(testItemList().WithTag("go"))).To(Equal([]int64{1, 2}))
+	Expect(itemIDs(testItemList().WithTag("cli"))).To(Equal([]int64{2}))
+	Expect(itemIDs(testItemList().WithTag("none"))).To(BeEmpty())
+}
+
+func TestItemListUntagged(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(itemIDs(testItemList().Untagged())).To(Equal([]int64{3}))
+}
+
+func TestItemListWithDomain(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(itemIDs(testItemList().WithDomain("example.com"))).To(Equal([]int64{1, 2}))
+	Expect(itemIDs(testItemList().WithDomain("example.org"))).To(Equal([]int64{3}))
+}
+
+func TestItemListWithAnyDomain(t *testing.T) {
+	RegisterTestingT(t)
+
+	var items []api.Item
+	err := json.Unmarshal([]byte(`[
+		{"item_id":"1","resolved_url":"https://www.nytimes.com/a"},
+		{"item_id":"2","resolved_url":"https://bbc.co.uk/b"},
+		{"item_id":"3","resolved_url":"https://example.com/c"},
+		{"item_id":"4","given_url":"https://news.bbc.co.uk/d"}
+	]`), &items)
+	Expect(err).To(BeNil())
+
+	Expect(itemIDs(api.ItemList(items).WithAnyDomain("nytimes.com", "www.BBC.co.uk"))).To(Equal([]int64{1, 2}))
+	Expect(itemIDs(api.ItemList(items).WithDomain("www.example.com"))).To(Equal([]int64{3}))
+	Expect(itemIDs(api.ItemList(items).WithAnyDomain())).To(BeEmpty())
+}
+
+func TestItemListFavorited(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(itemIDs(testItemList().Favorited())).To(Equal([]int64{1, 3}))
+}
+
+func TestItemListWithMinWords(t *testing.T) {
+	RegisterTestingT(t)
+
+	items := api.ItemList{
+		{ItemID: 1, WordCount: 1999},
+		{ItemID: 2, WordCount: 2000},
+		{ItemID: 3, WordCount: 2001},
+		{ItemID: 4},
+	}
+	Expect(itemIDs(items.WithMinWords(2000))).To(Equal([]int64{3}))
+	Expect(itemIDs(items.WithMinWords(0))).To(Equal([]int64{1, 2, 3}))
+}
+
+func TestItemListChaining(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(itemIDs(testItemList().WithTag("go").Favorited())).To(Equal([]int64{1}))
+	Expect(itemIDs(testItemList().Favorited().WithDomain("example.org"))).To(Equal([]int64{3}))
+	Expect(itemIDs(api.ItemList(nil).WithTag("go").Favorited())).To(BeEmpty())
+}
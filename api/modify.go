@@ -1,9 +1,33 @@
 package api
 
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrActionFailed is returned by ModifyConcurrent for an action which Pocket
+// reported as failed.
+var ErrActionFailed = errors.New("action failed")
+
+// ErrInvalidTag is returned when a tag name cannot be sent to Pocket, which
+// uses commas to separate tags and has no way to escape them.
+var ErrInvalidTag = errors.New("tag names must not contain commas")
+
 // Action represents one action in a bulk modify requests.
 type Action struct {
 	Action string `json:"action"`
-	ItemID int    `json:"item_id,string"`
+	ItemID int    `json:"item_id,string,omitempty"`
+	Tags   string `json:"tags,omitempty"`
+	Tag    string `json:"tag,omitempty"`
+	OldTag string `json:"old_tag,omitempty"`
+	NewTag string `json:"new_tag,omitempty"`
+	URL    string `json:"url,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Time   int64  `json:"time,omitempty"`
 }
 
 // NewArchiveAction creates an acrhive action.
@@ -14,11 +38,180 @@ func NewArchiveAction(itemID int) *Action {
 	}
 }
 
+// NewAddAction creates an action adding a URL, as described by options. Tags
+// in TagList containing commas are rejected with ErrInvalidTag.
+func NewAddAction(options *AddOption) (*Action, error) {
+	tags, err := options.joinedTags()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Action{
+		Action: "add",
+		URL:    options.URL,
+		Title:  options.Title,
+		Tags:   tags,
+		Time:   options.Time,
+	}, nil
+}
+
+// NewReaddAction creates a readd action, which moves an archived item back
+// to the unread list. Pocket reports false in ActionResults when the item
+// could not be found.
+func NewReaddAction(itemID int) *Action {
+	return &Action{
+		Action: "readd",
+		ItemID: itemID,
+	}
+}
+
+// NewFavoriteAction creates a favorite action.
+func NewFavoriteAction(itemID int) *Action {
+	return &Action{
+		Action: "favorite",
+		ItemID: itemID,
+	}
+}
+
+// NewUnfavoriteAction creates an unfavorite action.
+func NewUnfavoriteAction(itemID int) *Action {
+	return &Action{
+		Action: "unfavorite",
+		ItemID: itemID,
+	}
+}
+
+// NewDeleteAction creates a delete action. Deleting an item is permanent.
+func NewDeleteAction(itemID int) *Action {
+	return &Action{
+		Action: "delete",
+		ItemID: itemID,
+	}
+}
+
+// NewTagsAddAction creates an action adding tags to an item.
+func NewTagsAddAction(itemID int, tags []string) (*Action, error) {
+	return newTagsAction("tags_add", itemID, tags)
+}
+
+// NewTagsRemoveAction creates an action removing tags from an item.
+func NewTagsRemoveAction(itemID int, tags []string) (*Action, error) {
+	return newTagsAction("tags_remove", itemID, tags)
+}
+
+// NewTagsReplaceAction creates an action replacing all tags of an item.
+func NewTagsReplaceAction(itemID int, tags []string) (*Action, error) {
+	return newTagsAction("tags_replace", itemID, tags)
+}
+
+// NewTagsClearAction creates an action removing all tags from an item.
+func NewTagsClearAction(itemID int) *Action {
+	return &Action{
+		Action: "tags_clear",
+		ItemID: itemID,
+	}
+}
+
+// NewTagRenameAction creates an action renaming a tag on every item in the
+// account.
+func NewTagRenameAction(oldTag, newTag string) *Action {
+	return &Action{
+		Action: "tag_rename",
+		OldTag: oldTag,
+		NewTag: newTag,
+	}
+}
+
+// NewTagDeleteAction creates an action deleting a tag from every item in the
+// account.
+func NewTagDeleteAction(tag string) *Action {
+	return &Action{
+		Action: "tag_delete",
+		Tag:    tag,
+	}
+}
+
+func newTagsAction(action string, itemID int, tags []string) (*Action, error) {
+	joined, err := joinTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Action{
+		Action: action,
+		ItemID: itemID,
+		Tags:   joined,
+	}, nil
+}
+
+// joinTags joins tags into the comma-separated form the API expects.
+func joinTags(tags []string) (string, error) {
+	for _, tag := range tags {
+		if strings.Contains(tag, ",") {
+			return "", fmt.Errorf("%w: %q", ErrInvalidTag, tag)
+		}
+	}
+
+	return strings.Join(tags, ","), nil
+}
+
 // ModifyResult represents the modify API's result.
 type ModifyResult struct {
 	// The results for each of the requested actions.
-	ActionResults []bool
-	Status        int
+	ActionResults []bool `json:"action_results"`
+	// The error messages for each of the requested actions, empty for the
+	// actions which succeeded. Pocket omits these for some responses.
+	ActionErrors []string `json:"action_errors"`
+	Status       int      `json:"status"`
+
+	// The undecoded results, which hold the created items for add actions.
+	rawResults []json.RawMessage
+}
+
+type actionError struct {
+	Message string `json:"message"`
+}
+
+// UnmarshalJSON decodes the send API's response. Pocket reports each entry
+// of action_results as a boolean, or as the created item for a successful add
+// action, and each entry of action_errors as either null or an object carrying
+// a message.
+func (r *ModifyResult) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		ActionResults []json.RawMessage `json:"action_results"`
+		ActionErrors  []json.RawMessage `json:"action_errors"`
+		Status        int               `json:"status"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	r.Status = raw.Status
+	r.rawResults = raw.ActionResults
+	r.ActionResults = nil
+	r.ActionErrors = nil
+
+	for _, result := range raw.ActionResults {
+		var ok bool
+		if err := json.Unmarshal(result, &ok); err != nil {
+			ok = len(result) > 0 && result[0] == '{'
+		}
+		r.ActionResults = append(r.ActionResults, ok)
+	}
+
+	for _, e := range raw.ActionErrors {
+		var message string
+		if err := json.Unmarshal(e, &message); err != nil {
+			var obj actionError
+			if err := json.Unmarshal(e, &obj); err != nil {
+				return err
+			}
+			message = obj.Message
+		}
+		r.ActionErrors = append(r.ActionErrors, message)
+	}
+
+	return nil
 }
 
 type modifyAPIOptionsWithAuth struct {
@@ -26,17 +219,93 @@ type modifyAPIOptionsWithAuth struct {
 	authInfo
 }
 
-// Modify requests bulk modification on items.
+// Modify requests bulk modification on items. It is equivalent to
+// ModifyBatch.
 func (c *Client) Modify(actions ...*Action) (*ModifyResult, error) {
+	return c.ModifyBatch(actions...)
+}
+
+// ModifyBatch sends all of the actions in a single request, preserving their
+// order. The ActionResults of the returned result are aligned by index with
+// actions.
+func (c *Client) ModifyBatch(actions ...*Action) (*ModifyResult, error) {
+	return c.ModifyContext(context.Background(), actions...)
+}
+
+// ModifyContext is like ModifyBatch, but the request is bound to ctx so it can
+// be cancelled or given a deadline.
+func (c *Client) ModifyContext(ctx context.Context, actions ...*Action) (*ModifyResult, error) {
 	res := &ModifyResult{}
 	data := modifyAPIOptionsWithAuth{
 		authInfo: c.authInfo,
 		Actions:  actions,
 	}
-	err := PostJSON("/v3/send", data, res)
+	err := c.postJSON(ctx, "/send", data, res)
 	if err != nil {
 		return nil, err
 	}
 
 	return res, nil
 }
+
+// ModifyConcurrent sends each action in a request of its own, running at most
+// workers requests at a time, for servers which handle batches of actions
+// poorly. The returned errors are aligned by index with actions and are nil
+// for the actions which succeeded.
+func (c *Client) ModifyConcurrent(actions []*Action, workers int) []error {
+	return c.ModifyConcurrentContext(context.Background(), actions, workers)
+}
+
+// ModifyConcurrentContext is like ModifyConcurrent, but the requests are bound
+// to ctx. Once ctx is done, the actions not yet sent fail with its error.
+func (c *Client) ModifyConcurrentContext(ctx context.Context, actions []*Action, workers int) []error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	errs := make([]error, len(actions))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(actions); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = c.modifyOne(ctx, actions[i])
+			}
+		}()
+	}
+
+	for i := range actions {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return errs
+}
+
+func (c *Client) modifyOne(ctx context.Context, action *Action) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	res, err := c.ModifyContext(ctx, action)
+	if err != nil {
+		return err
+	}
+
+	if len(res.ActionResults) == 0 || !res.ActionResults[0] {
+		message := ""
+		if len(res.ActionErrors) > 0 {
+			message = res.ActionErrors[0]
+		}
+		if message == "" {
+			return fmt.Errorf("%w: %s", ErrActionFailed, action.Action)
+		}
+		return fmt.Errorf("%w: %s: %s", ErrActionFailed, action.Action, message)
+	}
+
+	return nil
+}
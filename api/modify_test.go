@@ -0,0 +1,365 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bvp/go-pocket/api"
+	. "github.com/onsi/gomega"
+)
+
+func TestModifyFavorite(t *testing.T) {
+	RegisterTestingT(t)
+
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Write([]byte(`{"action_results":[true],"status":1}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+	_, err := client.Modify(api.NewFavoriteAction(42))
+
+	Expect(err).To(BeNil())
+	Expect(body).To(ContainSubstring(`"action":"favorite"`))
+	Expect(body).To(ContainSubstring(`"item_id":"42"`))
+}
+
+func TestModifyUnfavorite(t *testing.T) {
+	RegisterTestingT(t)
+
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Write([]byte(`{"action_results":[true],"status":1}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+	_, err := client.Modify(api.NewUnfavoriteAction(42))
+
+	Expect(err).To(BeNil())
+	Expect(body).To(ContainSubstring(`"action":"unfavorite"`))
+}
+
+func TestModifyDelete(t *testing.T) {
+	RegisterTestingT(t)
+
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Write([]byte(`{"action_results":[true],"status":1}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+	res, err := client.Modify(api.NewDeleteAction(42))
+
+	Expect(err).To(BeNil())
+	Expect(body).To(ContainSubstring(`{"action":"delete","item_id":"42"}`))
+	Expect(res.Status).To(Equal(1))
+	Expect(res.ActionResults).To(Equal([]bool{true}))
+}
+
+func TestModifyDeleteFailed(t *testing.T) {
+	RegisterTestingT(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"action_results":[false],"status":1}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+	res, err := client.Modify(api.NewDeleteAction(42))
+
+	Expect(err).To(BeNil())
+	Expect(res.ActionResults).To(Equal([]bool{false}))
+}
+
+func TestModifyReadd(t *testing.T) {
+	RegisterTestingT(t)
+
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Write([]byte(`{"action_results":[true,false],"status":1}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+	res, err := client.Modify(api.NewReaddAction(42), api.NewReaddAction(43))
+
+	Expect(err).To(BeNil())
+	Expect(body).To(ContainSubstring(`{"action":"readd","item_id":"42"}`))
+	Expect(res.ActionResults).To(Equal([]bool{true, false}))
+}
+
+func TestModifyTags(t *testing.T) {
+	RegisterTestingT(t)
+
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Write([]byte(`{"action_results":[true],"status":1}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+
+	for name, newAction := range map[string]func(int, []string) (*api.Action, error){
+		"tags_add":     api.NewTagsAddAction,
+		"tags_remove":  api.NewTagsRemoveAction,
+		"tags_replace": api.NewTagsReplaceAction,
+	} {
+		action, err := newAction(42, []string{"go", "read later"})
+		Expect(err).To(BeNil())
+
+		_, err = client.Modify(action)
+
+		Expect(err).To(BeNil())
+		Expect(body).To(ContainSubstring(`"action":"` + name + `"`))
+		Expect(body).To(ContainSubstring(`"tags":"go,read later"`))
+	}
+}
+
+func TestModifyTagsRejectsCommas(t *testing.T) {
+	RegisterTestingT(t)
+
+	action, err := api.NewTagsAddAction(42, []string{"a,b"})
+
+	Expect(action).To(BeNil())
+	Expect(errors.Is(err, api.ErrInvalidTag)).To(BeTrue())
+}
+
+func TestNewAddActionRejectsCommas(t *testing.T) {
+	RegisterTestingT(t)
+
+	action, err := api.NewAddAction(&api.AddOption{URL: "https://example.com", TagList: []string{"go", "a,b"}})
+
+	Expect(action).To(BeNil())
+	Expect(errors.Is(err, api.ErrInvalidTag)).To(BeTrue())
+
+	action, err = api.NewAddAction(&api.AddOption{URL: "https://example.com", TagList: []string{"go", "cli"}})
+	Expect(err).To(BeNil())
+	Expect(action.Tags).To(Equal("go,cli"))
+}
+
+// addAction creates an add action for seeding test servers.
+func addAction(options *api.AddOption) *api.Action {
+	action, err := api.NewAddAction(options)
+	Expect(err).To(BeNil())
+	return action
+}
+
+func TestModifyTagsClear(t *testing.T) {
+	RegisterTestingT(t)
+
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Write([]byte(`{"action_results":[true],"status":1}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+	_, err := client.Modify(api.NewTagsClearAction(42))
+
+	Expect(err).To(BeNil())
+	Expect(body).To(ContainSubstring(`{"action":"tags_clear","item_id":"42"}`))
+}
+
+func TestModifyAccountScopedTags(t *testing.T) {
+	RegisterTestingT(t)
+
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Write([]byte(`{"action_results":[true,true],"status":1}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+	_, err := client.Modify(
+		api.NewTagRenameAction("golang", "go"),
+		api.NewTagDeleteAction("stale"),
+	)
+
+	Expect(err).To(BeNil())
+	Expect(body).To(ContainSubstring(`{"action":"tag_rename","old_tag":"golang","new_tag":"go"}`))
+	Expect(body).To(ContainSubstring(`{"action":"tag_delete","tag":"stale"}`))
+	Expect(body).NotTo(ContainSubstring(`item_id`))
+}
+
+func TestModifyBatch(t *testing.T) {
+	RegisterTestingT(t)
+
+	var body string
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Write([]byte(`{"action_results":[true,false],"status":1}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+	res, err := client.ModifyBatch(api.NewArchiveAction(1), api.NewFavoriteAction(2))
+
+	Expect(err).To(BeNil())
+	Expect(requests).To(Equal(1))
+	Expect(body).To(ContainSubstring(`"actions":[{"action":"archive","item_id":"1"},{"action":"favorite","item_id":"2"}]`))
+	Expect(res.ActionResults).To(Equal([]bool{true, false}))
+}
+
+func TestModifyResultWithErrors(t *testing.T) {
+	RegisterTestingT(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"action_results":[true,false,true],
+			"action_errors":[null,{"message":"Invalid item id","type":"Bad Request","code":422},null],
+			"status":1
+		}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+	res, err := client.Modify(api.NewArchiveAction(1), api.NewArchiveAction(2), api.NewArchiveAction(3))
+
+	Expect(err).To(BeNil())
+	Expect(res.Status).To(Equal(1))
+	Expect(res.ActionResults).To(Equal([]bool{true, false, true}))
+	Expect(res.ActionErrors).To(Equal([]string{"", "Invalid item id", ""}))
+}
+
+func TestModifyResultWithoutErrors(t *testing.T) {
+	RegisterTestingT(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"action_results":[false,true],"status":0}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+	res, err := client.Modify(api.NewArchiveAction(1), api.NewArchiveAction(2))
+
+	Expect(err).To(BeNil())
+	Expect(res.Status).To(Equal(0))
+	Expect(res.ActionResults).To(Equal([]bool{false, true}))
+	Expect(res.ActionErrors).To(BeEmpty())
+}
+
+func TestModifyConcurrent(t *testing.T) {
+	RegisterTestingT(t)
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		var body struct {
+			Actions []*api.Action `json:"actions"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Actions[0].ItemID == 13 {
+			w.Write([]byte(`{"action_results":[false],"action_errors":[{"message":"Item not found."}],"status":1}`))
+		} else {
+			w.Write([]byte(`{"action_results":[true],"status":1}`))
+		}
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+
+	actions := []*api.Action{}
+	for id := 1; id <= 20; id++ {
+		actions = append(actions, api.NewArchiveAction(id))
+	}
+
+	errs := client.ModifyConcurrent(actions, 4)
+	Expect(errs).To(HaveLen(20))
+	for i, err := range errs {
+		if i == 12 {
+			Expect(errors.Is(err, api.ErrActionFailed)).To(BeTrue())
+			Expect(err).To(MatchError("action failed: archive: Item not found."))
+		} else {
+			Expect(err).To(BeNil())
+		}
+	}
+	Expect(maxInFlight).To(Equal(4))
+}
+
+func TestModifyConcurrentCancel(t *testing.T) {
+	RegisterTestingT(t)
+
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"action_results":[true],"status":1}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	errs := client.ModifyConcurrentContext(ctx, []*api.Action{api.NewArchiveAction(1), api.NewArchiveAction(2)}, 2)
+	Expect(errs).To(HaveLen(2))
+	Expect(errors.Is(errs[0], context.Canceled)).To(BeTrue())
+	Expect(errors.Is(errs[1], context.Canceled)).To(BeTrue())
+	Expect(requests).To(Equal(0))
+}
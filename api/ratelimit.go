@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimit is the rate limit state Pocket reports with each response. Pocket
+// limits both the requests made by a user and those made with a consumer key.
+type RateLimit struct {
+	// UserLimit is the number of calls a user may make per hour, of which
+	// UserRemaining are left until they are replenished after UserReset.
+	UserLimit     int
+	UserRemaining int
+	UserReset     time.Duration
+
+	// KeyLimit is the number of calls a consumer key may make per hour, of
+	// which KeyRemaining are left until they are replenished after KeyReset.
+	KeyLimit     int
+	KeyRemaining int
+	KeyReset     time.Duration
+
+	// Observed is when the response carrying these values was received.
+	Observed time.Time
+}
+
+// ResetTime returns when the user's rate limit is replenished.
+func (r RateLimit) ResetTime() time.Time {
+	return r.Observed.Add(r.UserReset)
+}
+
+// LastRateLimit returns the rate limit reported with the client's most recent
+// response. It is the zero value until a response has been received.
+func (c *Client) LastRateLimit() RateLimit {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lastRateLimit
+}
+
+func parseRateLimit(resp *http.Response) RateLimit {
+	header := func(name string) int {
+		i, _ := strconv.Atoi(resp.Header.Get(name))
+		return i
+	}
+
+	return RateLimit{
+		UserLimit:     header("X-Limit-User-Limit"),
+		UserRemaining: header("X-Limit-User-Remaining"),
+		UserReset:     time.Duration(header("X-Limit-User-Reset")) * time.Second,
+		KeyLimit:      header("X-Limit-Key-Limit"),
+		KeyRemaining:  header("X-Limit-Key-Remaining"),
+		KeyReset:      time.Duration(header("X-Limit-Key-Reset")) * time.Second,
+		Observed:      time.Now(),
+	}
+}
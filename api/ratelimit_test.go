@@ -0,0 +1,45 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bvp/go-pocket/api"
+	. "github.com/onsi/gomega"
+)
+
+func TestLastRateLimit(t *testing.T) {
+	RegisterTestingT(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Limit-User-Limit", "320")
+		w.Header().Set("X-Limit-User-Remaining", "318")
+		w.Header().Set("X-Limit-User-Reset", "3600")
+		w.Header().Set("X-Limit-Key-Limit", "10000")
+		w.Header().Set("X-Limit-Key-Remaining", "9998")
+		w.Header().Set("X-Limit-Key-Reset", "1800")
+		w.Write([]byte(`{"list":{},"status":1}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+	Expect(client.LastRateLimit()).To(Equal(api.RateLimit{}))
+
+	before := time.Now()
+	_, err := client.Retrieve(&api.RetrieveOption{})
+	Expect(err).To(BeNil())
+
+	limit := client.LastRateLimit()
+	Expect(limit.UserLimit).To(Equal(320))
+	Expect(limit.UserRemaining).To(Equal(318))
+	Expect(limit.UserReset).To(Equal(time.Hour))
+	Expect(limit.KeyLimit).To(Equal(10000))
+	Expect(limit.KeyRemaining).To(Equal(9998))
+	Expect(limit.KeyReset).To(Equal(30 * time.Minute))
+	Expect(limit.ResetTime()).To(BeTemporally(">=", before.Add(time.Hour)))
+	Expect(limit.ResetTime()).To(BeTemporally("<=", time.Now().Add(time.Hour)))
+}
@@ -2,10 +2,21 @@ package api
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
 	"strconv"
-	"time"
 )
 
+// ErrInvalidOption is returned when a RetrieveOption holds a value Pocket does
+// not accept.
+var ErrInvalidOption = errors.New("invalid retrieve option")
+
+// defaultPageSize is the page size RetrieveAll uses when no Count is given.
+const defaultPageSize = 100
+
 // RetrieveOption is the options for retrieve API.
 type RetrieveOption struct {
 	State       State          `json:"state,omitempty"`
@@ -16,9 +27,15 @@ type RetrieveOption struct {
 	DetailType  DetailType     `json:"detailType,omitempty"`
 	Search      string         `json:"search,omitempty"`
 	Domain      string         `json:"domain,omitempty"`
-	Since       int            `json:"since,omitempty"`
-	Count       int            `json:"count,omitempty"`
-	Offset      int            `json:"offset,omitempty"`
+	// Since only returns items modified after the given unix time. Pass the
+	// Since of a previous RetrieveResult to fetch only the changes made since.
+	Since int64 `json:"since,omitempty"`
+
+	// Count limits the number of items returned; zero leaves it to the
+	// server. Offset skips that many items, and is only meaningful when
+	// Count is set.
+	Count  int `json:"count,omitempty"`
+	Offset int `json:"offset,omitempty"`
 }
 
 type State string
@@ -61,6 +78,28 @@ const (
 	FavoriteFilterFavorited                  = "1"
 )
 
+func (o *RetrieveOption) validate() error {
+	switch o.Sort {
+	case "", SortNewest, SortOldest, SortTitle, SortSite:
+	default:
+		return fmt.Errorf("%w: unknown sort %q", ErrInvalidOption, o.Sort)
+	}
+
+	switch o.ContentType {
+	case "", ContentTypeArticle, ContentTypeVideo, ContentTypeImage:
+	default:
+		return fmt.Errorf("%w: unknown content type %q", ErrInvalidOption, o.ContentType)
+	}
+
+	switch o.Favorite {
+	case FavoriteFilterUnspecified, FavoriteFilterUnfavorited, FavoriteFilterFavorited:
+	default:
+		return fmt.Errorf("%w: unknown favorite filter %q", ErrInvalidOption, o.Favorite)
+	}
+
+	return nil
+}
+
 type retrieveAPIOptionWithAuth struct {
 	*RetrieveOption
 	authInfo
@@ -70,97 +109,171 @@ type RetrieveResult struct {
 	List     map[string]Item
 	Status   int
 	Complete int
-	Since    int
-}
-
-type ItemStatus int
+	// Since is the server time of this response, to be passed as
+	// RetrieveOption.Since on the next call.
+	Since int64
+	// SearchMeta describes the search Pocket ran when RetrieveOption.Search
+	// was set, and is nil otherwise. Fields it does not model can be read
+	// from RawJSON.
+	SearchMeta *SearchMeta `json:"search_meta"`
 
-const (
-	ItemStatusUnread   ItemStatus = 0
-	ItemStatusArchived            = 1
-	ItemStatusDeleted             = 2
-)
-
-type ItemMediaAttachment int
-
-const (
-	ItemMediaAttachmentNoMedia  ItemMediaAttachment = 0
-	ItemMediaAttachmentHasMedia                     = 1
-	ItemMediaAttachmentIsMedia                      = 2
-)
+	raw json.RawMessage
+}
 
-type Item struct {
-	ItemID        int64      `json:"item_id,string"`
-	ResolvedId    int64      `json:"resolved_id,string"`
-	GivenURL      string     `json:"given_url"`
-	ResolvedURL   string     `json:"resolved_url"`
-	GivenTitle    string     `json:"given_title"`
-	ResolvedTitle string     `json:"resolved_title"`
-	Favorite      int        `json:",string"`
-	Status        ItemStatus `json:",string"`
-	Excerpt       string
-	IsArticle     int                 `json:"is_article,string"`
-	HasImage      ItemMediaAttachment `json:"has_image,string"`
-	HasVideo      ItemMediaAttachment `json:"has_video,string"`
-	WordCount     int                 `json:"word_count,string"`
-
-	// Fields for detailed response
-	Tags    map[string]map[string]interface{}
-	Authors map[string]map[string]interface{}
-	Images  map[string]map[string]interface{}
-	Videos  map[string]map[string]interface{}
-
-	// Fields that are not documented but exist
-	SortId        int  `json:"sort_id"`
-	TimeAdded     Time `json:"time_added"`
-	TimeUpdated   Time `json:"time_updated"`
-	TimeRead      Time `json:"time_read"`
-	TimeFavorited Time `json:"time_favorited"`
+// SearchMeta is the search_meta of a retrieve response to a search.
+type SearchMeta struct {
+	// SearchType is the kind of search Pocket ran, like "normal" for one
+	// matching titles and URLs.
+	SearchType string `json:"search_type"`
 }
 
-type Time time.Time
+// UnmarshalJSON decodes the retrieve API's response, keeping the undecoded
+// body for RawJSON. Pocket sends an empty list as an array rather than an
+// object, which is decoded as an empty List.
+func (r *RetrieveResult) UnmarshalJSON(b []byte) error {
+	type plainResult RetrieveResult
+	aux := struct {
+		*plainResult
+		List       json.RawMessage `json:"list"`
+		SearchMeta json.RawMessage `json:"search_meta"`
+	}{plainResult: (*plainResult)(r)}
 
-func (t *Time) UnmarshalJSON(b []byte) error {
-	i, err := strconv.ParseInt(string(bytes.Trim(b, `"`)), 10, 64)
-	if err != nil {
+	if err := json.Unmarshal(b, &aux); err != nil {
 		return err
 	}
 
-	*t = Time(time.Unix(i, 0))
+	// Anything but an object, like an empty array, means no metadata.
+	r.SearchMeta = nil
+	if meta := bytes.TrimSpace(aux.SearchMeta); len(meta) > 0 && meta[0] == '{' {
+		r.SearchMeta = &SearchMeta{}
+		if err := json.Unmarshal(meta, r.SearchMeta); err != nil {
+			return err
+		}
+	}
+
+	r.List = map[string]Item{}
+	list := bytes.TrimSpace(aux.List)
+	if len(list) > 0 && list[0] == '[' {
+		var items []Item
+		if err := json.Unmarshal(list, &items); err != nil {
+			return err
+		}
+		for _, item := range items {
+			r.List[strconv.FormatInt(item.ItemID, 10)] = item
+		}
+	} else if len(list) > 0 && !bytes.Equal(list, []byte("null")) {
+		if err := json.Unmarshal(list, &r.List); err != nil {
+			return err
+		}
+	}
 
+	r.raw = append(json.RawMessage(nil), b...)
 	return nil
 }
 
-// URL returns ResolvedURL or GivenURL
-func (item Item) URL() string {
-	url := item.ResolvedURL
-	if url == "" {
-		url = item.GivenURL
-	}
-	return url
+// Items returns the items of List in sort id order, which is the order
+// Pocket sorted them in.
+func (r *RetrieveResult) Items() []Item {
+	return sortedItems(r.List)
 }
 
-// Title returns ResolvedTitle or GivenTitle
-func (item Item) Title() string {
-	title := item.ResolvedTitle
-	if title == "" {
-		title = item.GivenTitle
-	}
-	return title
+// RawJSON returns the response as Pocket sent it, for reading fields which
+// RetrieveResult and Item do not model.
+func (r *RetrieveResult) RawJSON() json.RawMessage {
+	return r.raw
 }
 
 // Retrieve returns the in Pocket
 func (c *Client) Retrieve(options *RetrieveOption) (*RetrieveResult, error) {
+	return c.RetrieveContext(context.Background(), options)
+}
+
+// RetrieveContext is like Retrieve, but the request is bound to ctx so it can
+// be cancelled or given a deadline.
+func (c *Client) RetrieveContext(ctx context.Context, options *RetrieveOption) (*RetrieveResult, error) {
+	if options == nil {
+		options = &RetrieveOption{}
+	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+
 	data := retrieveAPIOptionWithAuth{
 		authInfo:       c.authInfo,
 		RetrieveOption: options,
 	}
 
 	res := &RetrieveResult{}
-	err := PostJSON("/v3/get", data, res)
+	err := c.postJSON(ctx, "/get", data, res)
 	if err != nil {
 		return nil, err
 	}
 
 	return res, nil
 }
+
+// RetrieveAll pages through every item matching options and returns them
+// all. See RetrieveEach for how pages are requested.
+func (c *Client) RetrieveAll(options *RetrieveOption) ([]Item, error) {
+	items := []Item{}
+	err := c.RetrieveEach(options, func(item Item) error {
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// RetrieveEach pages through every item matching options, advancing Offset by
+// Count until Pocket returns an empty list, and calls fn for each item. Count
+// defaults to 100 when unset. Items within each page are passed in sort id
+// order, and an item seen on an earlier page is not passed again. If fn
+// returns an error, paging stops and that error is returned.
+func (c *Client) RetrieveEach(options *RetrieveOption, fn func(Item) error) error {
+	page := RetrieveOption{}
+	if options != nil {
+		page = *options
+	}
+	if page.Count <= 0 {
+		page.Count = defaultPageSize
+	}
+
+	seen := map[int64]bool{}
+	for {
+		res, err := c.Retrieve(&page)
+		if err != nil {
+			return err
+		}
+
+		if len(res.List) == 0 {
+			return nil
+		}
+
+		for _, item := range res.Items() {
+			if seen[item.ItemID] {
+				continue
+			}
+			seen[item.ItemID] = true
+
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+
+		page.Offset += page.Count
+	}
+}
+
+func sortedItems(list map[string]Item) []Item {
+	items := make([]Item, 0, len(list))
+	for _, item := range list {
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].SortId < items[j].SortId })
+
+	return items
+}
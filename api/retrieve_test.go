@@ -0,0 +1,394 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bvp/go-pocket/api"
+	. "github.com/onsi/gomega"
+)
+
+func TestRetrievePagination(t *testing.T) {
+	RegisterTestingT(t)
+
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Write([]byte(`{"list":{},"status":1}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+
+	_, err := client.Retrieve(&api.RetrieveOption{Count: 30, Offset: 60})
+	Expect(err).To(BeNil())
+	Expect(body).To(ContainSubstring(`"count":30`))
+	Expect(body).To(ContainSubstring(`"offset":60`))
+
+	_, err = client.Retrieve(&api.RetrieveOption{})
+	Expect(err).To(BeNil())
+	Expect(body).NotTo(ContainSubstring(`"count"`))
+	Expect(body).NotTo(ContainSubstring(`"offset"`))
+}
+
+func TestRetrieveAll(t *testing.T) {
+	RegisterTestingT(t)
+
+	pages := []string{
+		`{"list":{"1":{"item_id":"1","sort_id":0},"2":{"item_id":"2","sort_id":1}},"status":1}`,
+		`{"list":{"3":{"item_id":"3","sort_id":0}},"status":1}`,
+		`{"list":{},"status":2}`,
+	}
+	offsets := []float64{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&data)
+		Expect(data["count"]).To(Equal(float64(2)))
+		offset, _ := data["offset"].(float64)
+		offsets = append(offsets, offset)
+		w.Write([]byte(pages[len(offsets)-1]))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+	options := &api.RetrieveOption{Count: 2}
+	items, err := client.RetrieveAll(options)
+
+	Expect(err).To(BeNil())
+	Expect(offsets).To(Equal([]float64{0, 2, 4}))
+	Expect(options.Offset).To(Equal(0))
+
+	ids := []int64{}
+	for _, item := range items {
+		ids = append(ids, item.ItemID)
+	}
+	Expect(ids).To(Equal([]int64{1, 2, 3}))
+}
+
+func TestRetrieveEach(t *testing.T) {
+	RegisterTestingT(t)
+
+	pages := []string{
+		`{"list":{"1":{"item_id":"1","sort_id":1},"2":{"item_id":"2","sort_id":0}},"status":1}`,
+		`{"list":{},"status":2}`,
+	}
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(pages[requests]))
+		requests++
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+
+	ids := []int64{}
+	err := client.RetrieveEach(&api.RetrieveOption{Count: 2}, func(item api.Item) error {
+		ids = append(ids, item.ItemID)
+		return nil
+	})
+
+	Expect(err).To(BeNil())
+	Expect(requests).To(Equal(2))
+	Expect(ids).To(Equal([]int64{2, 1}))
+}
+
+func TestRetrieveEachStopsEarly(t *testing.T) {
+	RegisterTestingT(t)
+
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"list":{"1":{"item_id":"1","sort_id":0},"2":{"item_id":"2","sort_id":1}},"status":1}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+
+	stop := errors.New("stop")
+	calls := 0
+	err := client.RetrieveEach(&api.RetrieveOption{Count: 2}, func(item api.Item) error {
+		calls++
+		return stop
+	})
+
+	Expect(err).To(Equal(stop))
+	Expect(calls).To(Equal(1))
+	Expect(requests).To(Equal(1))
+}
+
+func TestRetrieveSort(t *testing.T) {
+	RegisterTestingT(t)
+
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Write([]byte(`{"list":{},"status":1}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+
+	_, err := client.Retrieve(&api.RetrieveOption{Sort: api.SortOldest})
+	Expect(err).To(BeNil())
+	Expect(body).To(ContainSubstring(`"sort":"oldest"`))
+}
+
+func TestRetrieveRejectsInvalidSort(t *testing.T) {
+	RegisterTestingT(t)
+
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"list":{},"status":1}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+
+	_, err := client.Retrieve(&api.RetrieveOption{Sort: "random"})
+	Expect(errors.Is(err, api.ErrInvalidOption)).To(BeTrue())
+	Expect(requests).To(Equal(0))
+}
+
+func TestRetrieveContentType(t *testing.T) {
+	RegisterTestingT(t)
+
+	var data map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data = nil
+		json.NewDecoder(r.Body).Decode(&data)
+		w.Write([]byte(`{"list":{},"status":1}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+
+	_, err := client.Retrieve(&api.RetrieveOption{
+		ContentType: api.ContentTypeVideo,
+		Tag:         "watch",
+		Domain:      "youtube.com",
+	})
+	Expect(err).To(BeNil())
+	Expect(data).To(HaveKeyWithValue("contentType", "video"))
+	Expect(data).To(HaveKeyWithValue("tag", "watch"))
+	Expect(data).To(HaveKeyWithValue("domain", "youtube.com"))
+
+	_, err = client.Retrieve(&api.RetrieveOption{Tag: "watch"})
+	Expect(err).To(BeNil())
+	Expect(data).NotTo(HaveKey("contentType"))
+
+	_, err = client.Retrieve(&api.RetrieveOption{ContentType: "audio"})
+	Expect(errors.Is(err, api.ErrInvalidOption)).To(BeTrue())
+}
+
+func TestRetrieveFavorite(t *testing.T) {
+	RegisterTestingT(t)
+
+	var data map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data = nil
+		json.NewDecoder(r.Body).Decode(&data)
+		w.Write([]byte(`{"list":{},"status":1}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+
+	_, err := client.Retrieve(&api.RetrieveOption{Favorite: api.FavoriteFilterFavorited})
+	Expect(err).To(BeNil())
+	Expect(data).To(HaveKeyWithValue("favorite", "1"))
+
+	_, err = client.Retrieve(&api.RetrieveOption{Favorite: api.FavoriteFilterUnfavorited})
+	Expect(err).To(BeNil())
+	Expect(data).To(HaveKeyWithValue("favorite", "0"))
+
+	_, err = client.Retrieve(&api.RetrieveOption{Favorite: api.FavoriteFilterUnspecified})
+	Expect(err).To(BeNil())
+	Expect(data).NotTo(HaveKey("favorite"))
+}
+
+func TestRetrieveSince(t *testing.T) {
+	RegisterTestingT(t)
+
+	var data map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data = nil
+		json.NewDecoder(r.Body).Decode(&data)
+		w.Write([]byte(`{"list":{},"status":1,"since":1577836800}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+
+	res, err := client.Retrieve(&api.RetrieveOption{})
+	Expect(err).To(BeNil())
+	Expect(data).NotTo(HaveKey("since"))
+	Expect(res.Since).To(Equal(int64(1577836800)))
+
+	_, err = client.Retrieve(&api.RetrieveOption{Since: res.Since})
+	Expect(err).To(BeNil())
+	Expect(data).To(HaveKeyWithValue("since", float64(1577836800)))
+}
+
+func TestRetrieveRawJSON(t *testing.T) {
+	RegisterTestingT(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"list":{"1":{"item_id":"1","listen_duration_estimate":42}},"status":1,"search_meta":{"search_type":"normal"}}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+
+	res, err := client.Retrieve(&api.RetrieveOption{})
+	Expect(err).To(BeNil())
+	Expect(res.List).To(HaveKey("1"))
+
+	var raw struct {
+		List map[string]struct {
+			ListenDurationEstimate int `json:"listen_duration_estimate"`
+		} `json:"list"`
+		SearchMeta struct {
+			SearchType string `json:"search_type"`
+		} `json:"search_meta"`
+	}
+	Expect(json.Unmarshal(res.RawJSON(), &raw)).To(Succeed())
+	Expect(raw.List["1"].ListenDurationEstimate).To(Equal(42))
+	Expect(raw.SearchMeta.SearchType).To(Equal("normal"))
+}
+
+func TestRetrieveResultEmptyListArray(t *testing.T) {
+	RegisterTestingT(t)
+
+	var res api.RetrieveResult
+	Expect(json.Unmarshal([]byte(`{"status":2,"complete":1,"list":[],"since":1577836800}`), &res)).To(Succeed())
+	Expect(res.List).NotTo(BeNil())
+	Expect(res.List).To(BeEmpty())
+	Expect(res.Status).To(Equal(2))
+	Expect(res.Since).To(Equal(int64(1577836800)))
+
+	res = api.RetrieveResult{}
+	Expect(json.Unmarshal([]byte(`{"status":1,"list":{"1":{"item_id":"1"}}}`), &res)).To(Succeed())
+	Expect(res.List).To(HaveKey("1"))
+}
+
+func TestRetrieveSearchMeta(t *testing.T) {
+	RegisterTestingT(t)
+
+	var search string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var options map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&options)
+		search, _ = options["search"].(string)
+		if search == "" {
+			w.Write([]byte(`{"status":1,"list":{"1":{"item_id":"1"}}}`))
+			return
+		}
+		w.Write([]byte(`{"status":1,"list":{"1":{"item_id":"1"}},"search_meta":{"search_type":"normal"}}`))
+	}))
+	defer ts.Close()
+
+	client := api.NewClient("consumer", "token")
+	client.BaseURL = ts.URL + "/v3"
+
+	res, err := client.Retrieve(&api.RetrieveOption{Search: "go"})
+	Expect(err).To(BeNil())
+	Expect(search).To(Equal("go"))
+	Expect(res.SearchMeta).To(Equal(&api.SearchMeta{SearchType: "normal"}))
+
+	res, err = client.Retrieve(&api.RetrieveOption{})
+	Expect(err).To(BeNil())
+	Expect(res.SearchMeta).To(BeNil())
+
+	res = &api.RetrieveResult{}
+	Expect(json.Unmarshal([]byte(`{"status":1,"list":[],"search_meta":[]}`), res)).To(Succeed())
+	Expect(res.SearchMeta).To(BeNil())
+}
+
+func TestRetrieveAllEmptyListArray(t *testing.T) {
+	RegisterTestingT(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":2,"complete":1,"list":[]}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+
+	items, err := client.RetrieveAll(&api.RetrieveOption{Tag: "nothing"})
+	Expect(err).To(BeNil())
+	Expect(items).To(BeEmpty())
+}
+
+func TestRetrieveResultItems(t *testing.T) {
+	RegisterTestingT(t)
+
+	var res api.RetrieveResult
+	Expect(json.Unmarshal([]byte(`{"list":{
+		"10":{"item_id":"10","sort_id":2},
+		"20":{"item_id":"20","sort_id":0},
+		"30":{"item_id":"30","sort_id":1}
+	}}`), &res)).To(Succeed())
+
+	items := res.Items()
+	Expect(items).To(HaveLen(3))
+	Expect(items[0].ItemID).To(Equal(int64(20)))
+	Expect(items[1].ItemID).To(Equal(int64(30)))
+	Expect(items[2].ItemID).To(Equal(int64(10)))
+
+	Expect((&api.RetrieveResult{}).Items()).To(BeEmpty())
+}
+
+func TestRetrieveContextCancel(t *testing.T) {
+	RegisterTestingT(t)
+
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.RetrieveContext(ctx, &api.RetrieveOption{})
+
+	Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+	Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy controls how a Client retries requests which fail with a 503 or
+// because the rate limit was hit.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts made for a request,
+	// including the first one.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry, doubling for each
+	// following one. When Pocket reports when the rate limit is replenished,
+	// that is waited for instead.
+	Backoff time.Duration
+}
+
+func (p *RetryPolicy) shouldRetry(attempt int, resp *http.Response, err error) bool {
+	if p == nil || attempt >= p.MaxAttempts || resp == nil || err == nil {
+		return false
+	}
+
+	switch resp.StatusCode {
+	case http.StatusServiceUnavailable, http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+		return isRateLimited(parseRateLimit(resp))
+	}
+
+	return false
+}
+
+func (p *RetryPolicy) delay(attempt int, limit RateLimit) time.Duration {
+	if isRateLimited(limit) {
+		return rateLimitDelay(limit)
+	}
+
+	return p.Backoff << uint(attempt-1)
+}
+
+// rateLimitDelay returns how long to wait until the exhausted one of the rate
+// limits is replenished.
+func rateLimitDelay(limit RateLimit) time.Duration {
+	if limit.UserRemaining == 0 && limit.UserLimit > 0 {
+		return limit.UserReset
+	}
+	return limit.KeyReset
+}
+
+func isRateLimited(limit RateLimit) bool {
+	return (limit.UserLimit > 0 && limit.UserRemaining == 0) ||
+		(limit.KeyLimit > 0 && limit.KeyRemaining == 0)
+}
+
+// sleepContext waits for d, returning early with the context's error if ctx
+// is done first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
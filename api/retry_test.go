@@ -0,0 +1,115 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bvp/go-pocket/api"
+	. "github.com/onsi/gomega"
+)
+
+func TestRetryAfterUnavailable(t *testing.T) {
+	RegisterTestingT(t)
+
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(503)
+			return
+		}
+		w.Write([]byte(`{"action_results":[true],"status":1}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+	client.RetryPolicy = &api.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+
+	res, err := client.Modify(api.NewArchiveAction(1))
+
+	Expect(err).To(BeNil())
+	Expect(res.ActionResults).To(Equal([]bool{true}))
+	Expect(requests).To(Equal(2))
+}
+
+func TestRetryHonorsRateLimitReset(t *testing.T) {
+	RegisterTestingT(t)
+
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("X-Limit-User-Limit", "320")
+			w.Header().Set("X-Limit-User-Remaining", "0")
+			w.Header().Set("X-Limit-User-Reset", "1")
+			w.WriteHeader(403)
+			return
+		}
+		w.Write([]byte(`{"action_results":[true],"status":1}`))
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+	client.RetryPolicy = &api.RetryPolicy{MaxAttempts: 2, Backoff: time.Hour}
+
+	start := time.Now()
+	_, err := client.Modify(api.NewArchiveAction(1))
+
+	Expect(err).To(BeNil())
+	Expect(requests).To(Equal(2))
+	Expect(time.Since(start)).To(BeNumerically(">=", time.Second))
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	RegisterTestingT(t)
+
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(503)
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+	client.RetryPolicy = &api.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+
+	_, err := client.Modify(api.NewArchiveAction(1))
+
+	var apiErr *api.APIError
+	Expect(errors.As(err, &apiErr)).To(BeTrue())
+	Expect(apiErr.StatusCode).To(Equal(503))
+	Expect(requests).To(Equal(3))
+}
+
+func TestRetryRespectsContext(t *testing.T) {
+	RegisterTestingT(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(503)
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	client := api.NewClient("consumer", "token")
+	client.RetryPolicy = &api.RetryPolicy{MaxAttempts: 3, Backoff: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.ModifyContext(ctx, api.NewArchiveAction(1))
+
+	Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+	Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+}
@@ -0,0 +1,75 @@
+package api
+
+// SyncDelta is a batch of the changes Sync found, split by what happened to
+// each item.
+type SyncDelta struct {
+	// Added holds the unread items which were added or changed. On the first
+	// sync, it holds every item, archived ones included.
+	Added []Item
+	// Archived holds the items which were archived or changed in the archive.
+	Archived []Item
+	// Deleted holds the ids of the items which were deleted.
+	Deleted []int64
+}
+
+// Sync reports the changes made since a previous call to fn, in one SyncDelta
+// per page of results; fn is not called when nothing changed. A since of zero
+// reports every item as added. Items come with complete details.
+//
+// Sync returns the cursor to pass as since on the next call. If retrieving or
+// fn fails, paging stops and since is returned unchanged along with the error,
+// so that the next call starts over from the same point.
+func (c *Client) Sync(since int64, fn func(delta SyncDelta) error) (int64, error) {
+	page := RetrieveOption{
+		State:      StateAll,
+		DetailType: DetailTypeComplete,
+		Since:      since,
+		Count:      defaultPageSize,
+	}
+
+	newSince := int64(0)
+	seen := map[int64]bool{}
+	for {
+		res, err := c.Retrieve(&page)
+		if err != nil {
+			return since, err
+		}
+
+		// The time of the first response is kept, so that changes made
+		// while paging are reported again next time rather than missed.
+		if newSince == 0 {
+			newSince = res.Since
+		}
+
+		if len(res.List) == 0 {
+			return newSince, nil
+		}
+
+		delta := SyncDelta{}
+		for _, item := range res.Items() {
+			if seen[item.ItemID] {
+				continue
+			}
+			seen[item.ItemID] = true
+
+			switch {
+			case since == 0:
+				delta.Added = append(delta.Added, item)
+			case item.Status == ItemStatusDeleted:
+				delta.Deleted = append(delta.Deleted, item.ItemID)
+			case item.Status == ItemStatusArchived:
+				delta.Archived = append(delta.Archived, item)
+			default:
+				delta.Added = append(delta.Added, item)
+			}
+		}
+
+		if len(delta.Added)+len(delta.Archived)+len(delta.Deleted) > 0 {
+			if err := fn(delta); err != nil {
+				return since, err
+			}
+		}
+
+		page.Offset += page.Count
+	}
+}
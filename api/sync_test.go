@@ -0,0 +1,79 @@
+package api_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bvp/go-pocket/api"
+	"github.com/bvp/go-pocket/api/apitest"
+	. "github.com/onsi/gomega"
+)
+
+func TestSync(t *testing.T) {
+	RegisterTestingT(t)
+
+	server := apitest.NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	_, err := client.ModifyBatch(
+		addAction(&api.AddOption{URL: "https://example.com/1"}),
+		addAction(&api.AddOption{URL: "https://example.com/2"}),
+		addAction(&api.AddOption{URL: "https://example.com/3"}),
+		api.NewArchiveAction(3),
+	)
+	Expect(err).To(BeNil())
+
+	deltas := []api.SyncDelta{}
+	collect := func(delta api.SyncDelta) error {
+		deltas = append(deltas, delta)
+		return nil
+	}
+
+	since, err := client.Sync(0, collect)
+	Expect(err).To(BeNil())
+	Expect(since).NotTo(BeZero())
+	Expect(deltas).To(HaveLen(1))
+	Expect(itemIDs(deltas[0].Added)).To(ConsistOf(int64(1), int64(2), int64(3)))
+	Expect(deltas[0].Archived).To(BeEmpty())
+	Expect(deltas[0].Deleted).To(BeEmpty())
+
+	_, err = client.ModifyBatch(
+		api.NewArchiveAction(1),
+		api.NewDeleteAction(2),
+		addAction(&api.AddOption{URL: "https://example.com/4"}),
+	)
+	Expect(err).To(BeNil())
+
+	deltas = nil
+	next, err := client.Sync(since, collect)
+	Expect(err).To(BeNil())
+	Expect(next).To(BeNumerically(">", since))
+	Expect(deltas).To(HaveLen(1))
+	Expect(itemIDs(deltas[0].Added)).To(Equal([]int64{4}))
+	Expect(itemIDs(deltas[0].Archived)).To(Equal([]int64{1}))
+	Expect(deltas[0].Deleted).To(Equal([]int64{2}))
+
+	// Nothing changed since.
+	deltas = nil
+	last, err := client.Sync(next, collect)
+	Expect(err).To(BeNil())
+	Expect(last).To(Equal(next))
+	Expect(deltas).To(BeEmpty())
+}
+
+func TestSyncCallbackError(t *testing.T) {
+	RegisterTestingT(t)
+
+	server := apitest.NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	_, err := client.Add(&api.AddOption{URL: "https://example.com/1"})
+	Expect(err).To(BeNil())
+
+	failed := errors.New("mirror is full")
+	since, err := client.Sync(42, func(api.SyncDelta) error { return failed })
+	Expect(err).To(Equal(failed))
+	Expect(since).To(Equal(int64(42)))
+}
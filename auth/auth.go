@@ -1,7 +1,13 @@
 package auth
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 
 	"github.com/bvp/go-pocket/api"
@@ -16,9 +22,59 @@ type Authorization struct {
 	Username    string `json:"username"`
 }
 
+// AuthError is returned when Pocket rejects an authentication request, for
+// example because the consumer key is invalid. Code and Message come from the
+// X-Error-Code and X-Error response headers.
+type AuthError struct {
+	StatusCode int
+	Code       int
+	Message    string
+
+	err error
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("authentication failed (%d): %s", e.Code, e.Message)
+}
+
+// Unwrap returns the underlying *api.APIError.
+func (e *AuthError) Unwrap() error {
+	return e.err
+}
+
+// authError converts API errors into an *AuthError, passing any other errors
+// through unchanged.
+func authError(err error) error {
+	var apiErr *api.APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+
+	return &AuthError{
+		StatusCode: apiErr.StatusCode,
+		Code:       apiErr.Code,
+		Message:    apiErr.Message,
+		err:        err,
+	}
+}
+
 func ObtainRequestToken(consumerKey, redirectURL string) (*RequestToken, error) {
+	return ObtainRequestTokenContext(context.Background(), consumerKey, redirectURL)
+}
+
+// ObtainRequestTokenContext is like ObtainRequestToken, but the request is
+// bound to ctx.
+func ObtainRequestTokenContext(ctx context.Context, consumerKey, redirectURL string) (*RequestToken, error) {
+	return ObtainRequestTokenWithHTTP(ctx, nil, consumerKey, redirectURL)
+}
+
+// ObtainRequestTokenWithHTTP is like ObtainRequestTokenContext, but the
+// request is made with hc. A nil hc uses api.DefaultClient.
+func ObtainRequestTokenWithHTTP(ctx context.Context, hc *http.Client, consumerKey, redirectURL string) (*RequestToken, error) {
 	res := &RequestToken{}
-	err := api.PostJSON(
+	err := api.PostJSONWithHTTP(
+		ctx,
+		hc,
 		"/v3/oauth/request",
 		map[string]string{
 			"consumer_key": consumerKey,
@@ -27,15 +83,29 @@ func ObtainRequestToken(consumerKey, redirectURL string) (*RequestToken, error)
 		res,
 	)
 	if err != nil {
-		return nil, err
+		return nil, authError(err)
 	}
 
 	return res, nil
 }
 
 func ObtainAccessToken(consumerKey string, requestToken *RequestToken) (*Authorization, error) {
+	return ObtainAccessTokenContext(context.Background(), consumerKey, requestToken)
+}
+
+// ObtainAccessTokenContext is like ObtainAccessToken, but the request is
+// bound to ctx.
+func ObtainAccessTokenContext(ctx context.Context, consumerKey string, requestToken *RequestToken) (*Authorization, error) {
+	return ObtainAccessTokenWithHTTP(ctx, nil, consumerKey, requestToken)
+}
+
+// ObtainAccessTokenWithHTTP is like ObtainAccessTokenContext, but the request
+// is made with hc. A nil hc uses api.DefaultClient.
+func ObtainAccessTokenWithHTTP(ctx context.Context, hc *http.Client, consumerKey string, requestToken *RequestToken) (*Authorization, error) {
 	res := &Authorization{}
-	err := api.PostJSON(
+	err := api.PostJSONWithHTTP(
+		ctx,
+		hc,
 		"/v3/oauth/authorize",
 		map[string]string{
 			"consumer_key": consumerKey,
@@ -44,7 +114,7 @@ func ObtainAccessToken(consumerKey string, requestToken *RequestToken) (*Authori
 		res,
 	)
 	if err != nil {
-		return nil, err
+		return nil, authError(err)
 	}
 
 	return res, nil
@@ -54,3 +124,38 @@ func GenerateAuthorizationURL(requestToken *RequestToken, redirectURL string) st
 	values := url.Values{"request_token": {requestToken.Code}, "redirect_uri": {redirectURL}}
 	return fmt.Sprintf("%s/auth/authorize?%s", api.Origin, values.Encode())
 }
+
+// GenerateAuthorizationURLWithState is like GenerateAuthorizationURL, but adds
+// a state parameter to the redirect URL. Pocket passes it back unchanged when
+// redirecting, so the callback can be checked with VerifyState to make sure it
+// belongs to an authorization this application started.
+func GenerateAuthorizationURLWithState(requestToken *RequestToken, redirectURL, state string) (string, error) {
+	redirect, err := url.Parse(redirectURL)
+	if err != nil {
+		return "", err
+	}
+
+	query := redirect.Query()
+	query.Set("state", state)
+	redirect.RawQuery = query.Encode()
+
+	return GenerateAuthorizationURL(requestToken, redirect.String()), nil
+}
+
+// NewState returns a random value suitable as the state parameter of
+// GenerateAuthorizationURLWithState.
+func NewState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// VerifyState reports whether the callback URL Pocket redirected to carries
+// the expected state.
+func VerifyState(callbackURL *url.URL, state string) bool {
+	got := callbackURL.Query().Get("state")
+	return state != "" && subtle.ConstantTimeCompare([]byte(got), []byte(state)) == 1
+}
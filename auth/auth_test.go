@@ -1,10 +1,16 @@
 package auth_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bvp/go-pocket/api"
 	"github.com/bvp/go-pocket/auth"
@@ -28,3 +34,136 @@ func TestObtainRequestToken(t *testing.T) {
 	Expect(err).To(BeNil())
 	Expect(res.Code).To(Equal(theCode))
 }
+
+func TestObtainAccessTokenContextCancel(t *testing.T) {
+	RegisterTestingT(t)
+
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	api.Origin = ts.URL
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := auth.ObtainAccessTokenContext(ctx, "", &auth.RequestToken{Code: "code"})
+
+	Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+	Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+}
+
+func TestObtainRequestTokenAuthError(t *testing.T) {
+	RegisterTestingT(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Error-Code", "152")
+		w.Header().Set("X-Error", "Rejected consumer key")
+		w.WriteHeader(403)
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	_, err := auth.ObtainRequestToken("bad-key", "http://www.example.com/")
+
+	var authErr *auth.AuthError
+	Expect(errors.As(err, &authErr)).To(BeTrue())
+	Expect(authErr.StatusCode).To(Equal(403))
+	Expect(authErr.Code).To(Equal(152))
+	Expect(authErr.Message).To(Equal("Rejected consumer key"))
+
+	var apiErr *api.APIError
+	Expect(errors.As(err, &apiErr)).To(BeTrue())
+}
+
+func TestObtainAccessTokenAuthError(t *testing.T) {
+	RegisterTestingT(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Error-Code", "158")
+		w.Header().Set("X-Error", "User rejected code.")
+		w.WriteHeader(403)
+	}))
+	defer ts.Close()
+
+	api.Origin = ts.URL
+
+	_, err := auth.ObtainAccessToken("key", &auth.RequestToken{Code: "code"})
+
+	var authErr *auth.AuthError
+	Expect(errors.As(err, &authErr)).To(BeTrue())
+	Expect(authErr.Code).To(Equal(158))
+}
+
+type stubTransport struct {
+	requests []*http.Request
+	body     string
+}
+
+func (t *stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests = append(t.requests, req)
+	return &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader(t.body)),
+		Request:    req,
+	}, nil
+}
+
+func TestObtainTokensWithHTTP(t *testing.T) {
+	RegisterTestingT(t)
+
+	api.Origin = "https://pocket.invalid"
+
+	transport := &stubTransport{body: `{"code":"the-code","access_token":"the-token","username":"someone"}`}
+	hc := &http.Client{Transport: transport}
+
+	requestToken, err := auth.ObtainRequestTokenWithHTTP(context.Background(), hc, "key", "http://www.example.com/")
+	Expect(err).To(BeNil())
+	Expect(requestToken.Code).To(Equal("the-code"))
+
+	authorization, err := auth.ObtainAccessTokenWithHTTP(context.Background(), hc, "key", requestToken)
+	Expect(err).To(BeNil())
+	Expect(authorization.AccessToken).To(Equal("the-token"))
+	Expect(authorization.Username).To(Equal("someone"))
+
+	Expect(transport.requests).To(HaveLen(2))
+	Expect(transport.requests[0].URL.String()).To(Equal("https://pocket.invalid/v3/oauth/request"))
+	Expect(transport.requests[1].URL.String()).To(Equal("https://pocket.invalid/v3/oauth/authorize"))
+}
+
+func TestGenerateAuthorizationURLWithState(t *testing.T) {
+	RegisterTestingT(t)
+
+	api.Origin = "https://pocket.invalid"
+
+	state, err := auth.NewState()
+	Expect(err).To(BeNil())
+	Expect(state).NotTo(BeEmpty())
+
+	authURL, err := auth.GenerateAuthorizationURLWithState(
+		&auth.RequestToken{Code: "code"}, "http://127.0.0.1:8080/callback?app=1", state)
+	Expect(err).To(BeNil())
+
+	u, err := url.Parse(authURL)
+	Expect(err).To(BeNil())
+	Expect(u.Query().Get("request_token")).To(Equal("code"))
+
+	// Pocket redirects to the redirect_uri as given.
+	callback, err := url.Parse(u.Query().Get("redirect_uri"))
+	Expect(err).To(BeNil())
+	Expect(callback.Path).To(Equal("/callback"))
+	Expect(callback.Query().Get("app")).To(Equal("1"))
+
+	Expect(auth.VerifyState(callback, state)).To(BeTrue())
+	Expect(auth.VerifyState(callback, "other")).To(BeFalse())
+
+	noState, _ := url.Parse("http://127.0.0.1:8080/callback")
+	Expect(auth.VerifyState(noState, state)).To(BeFalse())
+	Expect(auth.VerifyState(noState, "")).To(BeFalse())
+}
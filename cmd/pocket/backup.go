@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/bvp/go-pocket/api"
+)
+
+func commandBackup(arguments map[string]interface{}, client *api.Client) error {
+	options := &api.RetrieveOption{
+		State:      api.StateAll,
+		DetailType: api.DetailTypeComplete,
+	}
+
+	items, err := client.RetrieveAll(options)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(items)
+}
+
+// commandRestore adds the items of a backup again, with their tags and the
+// times they were added, then archives and favorites them as they were. With
+// --dry-run only the add actions are printed, as the ids of the items to
+// archive and favorite are not known until Pocket creates them.
+func commandRestore(arguments map[string]interface{}, client *api.Client) error {
+	path, _ := arguments["<file>"].(string)
+
+	items := []api.Item{}
+	err := loadJSONFromFile(path, &items)
+	if err != nil {
+		return err
+	}
+
+	options := make([]*api.AddOption, len(items))
+	for i, item := range items {
+		options[i] = &api.AddOption{
+			URL:     item.URL(),
+			Title:   item.Title(),
+			TagList: item.TagNames(),
+		}
+		if added := item.TimeAdded.Time(); !added.IsZero() {
+			options[i].Time = added.Unix()
+		}
+	}
+
+	if dryRun {
+		return printAddActions(options)
+	}
+
+	results, err := client.AddBatch(options)
+	if err != nil {
+		return err
+	}
+
+	actions := []*api.Action{}
+	failed := 0
+	for i, res := range results {
+		if res == nil {
+			fmt.Fprintf(stdout, "Failed to restore %s\n", options[i].URL)
+			failed++
+			continue
+		}
+
+		itemID := int(res.ItemID)
+		if items[i].Status == api.ItemStatusArchived {
+			actions = append(actions, api.NewArchiveAction(itemID))
+		}
+		if items[i].Favorite == 1 {
+			actions = append(actions, api.NewFavoriteAction(itemID))
+		}
+	}
+
+	// Pocket caps the actions of a request, as for AddBatch.
+	size := client.BatchSize
+	if size <= 0 {
+		size = api.DefaultBatchSize
+	}
+	for start := 0; start < len(actions); start += size {
+		batch := actions[start:]
+		if len(batch) > size {
+			batch = batch[:size]
+		}
+
+		res, err := client.ModifyBatch(batch...)
+		if err != nil {
+			return err
+		}
+		for i, ok := range res.ActionResults {
+			if !ok && i < len(batch) {
+				fmt.Fprintf(stderr, "Could not %s item %d\n", batch[i].Action, batch[i].ItemID)
+			}
+		}
+	}
+
+	fmt.Fprintf(stdout, "Restored %d items, %d failed\n", len(results)-failed, failed)
+	return nil
+}
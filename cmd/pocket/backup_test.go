@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bvp/go-pocket/api"
+	"github.com/bvp/go-pocket/api/apitest"
+	. "github.com/onsi/gomega"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	from := apitest.NewServer()
+	defer from.Close()
+	client := from.Client()
+
+	_, err = client.ModifyBatch(
+		addAction(&api.AddOption{URL: "https://example.com/1", Title: "First", Tags: "go,cli", Time: 1577836800}),
+		addAction(&api.AddOption{URL: "https://example.com/2", Title: "Second", Time: 1577836900}),
+		api.NewArchiveAction(2),
+		api.NewFavoriteAction(2),
+	)
+	Expect(err).To(BeNil())
+
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"backup"})
+	Expect(err).To(BeNil())
+	Expect(commandBackup(arguments, client)).To(Succeed())
+
+	path := filepath.Join(dir, "backup.json")
+	Expect(ioutil.WriteFile(path, out.Bytes(), 0600)).To(Succeed())
+
+	to := apitest.NewServer()
+	defer to.Close()
+	client = to.Client()
+
+	out.Reset()
+	arguments, err = parseArguments([]string{"restore", path})
+	Expect(err).To(BeNil())
+	Expect(commandRestore(arguments, client)).To(Succeed())
+	Expect(out.String()).To(Equal("Restored 2 items, 0 failed\n"))
+
+	// The backup lists the newest items first, which are restored first.
+	items := to.Items()
+	Expect(items).To(HaveLen(2))
+	items[0], items[1] = items[1], items[0]
+
+	Expect(items[0].URL()).To(Equal("https://example.com/1"))
+	Expect(items[0].Title()).To(Equal("First"))
+	Expect(items[0].TagNames()).To(Equal([]string{"cli", "go"}))
+	Expect(items[0].TimeAdded.Time().Unix()).To(Equal(int64(1577836800)))
+	Expect(items[0].Status).To(Equal(api.ItemStatusUnread))
+
+	Expect(items[1].URL()).To(Equal("https://example.com/2"))
+	Expect(items[1].TimeAdded.Time().Unix()).To(Equal(int64(1577836900)))
+	Expect(items[1].Status).To(Equal(api.ItemStatus(api.ItemStatusArchived)))
+	Expect(items[1].Favorite).To(Equal(1))
+}
+
+func TestRestoreBatches(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "backup.json")
+	Expect(ioutil.WriteFile(path, []byte(`[
+		{"item_id":"1","given_url":"https://example.com/1","status":"1"},
+		{"item_id":"2","given_url":"https://example.com/2","status":"1"},
+		{"item_id":"3","given_url":"https://example.com/3","status":"1"}
+	]`), 0600)).To(Succeed())
+
+	var sizes []int
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Actions []*api.Action `json:"actions"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		sizes = append(sizes, len(body.Actions))
+
+		results := []interface{}{}
+		for i, action := range body.Actions {
+			if action.Action == "add" {
+				results = append(results, map[string]string{"item_id": action.URL[len(action.URL)-1:]})
+			} else {
+				results = append(results, i == 0)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"action_results": results, "status": 1})
+	})
+	defer ts.Close()
+	client.BatchSize = 2
+	out := captureStdout()
+	defer resetStdout()
+	errOut := &bytes.Buffer{}
+	stderr = errOut
+	defer func() { stderr = os.Stderr }()
+
+	arguments, err := parseArguments([]string{"restore", path})
+	Expect(err).To(BeNil())
+	Expect(commandRestore(arguments, client)).To(Succeed())
+
+	// Two requests of adds, then two of archive actions.
+	Expect(sizes).To(Equal([]int{2, 1, 2, 1}))
+	Expect(out.String()).To(Equal("Restored 3 items, 0 failed\n"))
+	Expect(errOut.String()).To(Equal("Could not archive item 2\n"))
+}
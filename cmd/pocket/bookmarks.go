@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bvp/go-pocket/api"
+)
+
+const bookmarksHeader = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Pocket</TITLE>
+<H1>Pocket</H1>
+<DL><p>
+`
+
+const bookmarksFooter = `</DL><p>
+`
+
+func commandExport(arguments map[string]interface{}, client *api.Client) error {
+	options := &api.RetrieveOption{
+		State: api.StateAll,
+		// Tags are only included with complete details.
+		DetailType: api.DetailTypeComplete,
+	}
+
+	items, err := client.RetrieveAll(options)
+	if err != nil {
+		return err
+	}
+
+	cleanURLs, _ := arguments["--clean-urls"].(bool)
+	return writeBookmarks(stdout, items, cleanURLs)
+}
+
+// writeBookmarks writes items in the Netscape bookmark file format which
+// browsers import, with the unread and archived items in separate folders.
+// With cleanURLs, tracking parameters are removed from the links.
+func writeBookmarks(w io.Writer, items []api.Item, cleanURLs bool) error {
+	unread := []api.Item{}
+	archived := []api.Item{}
+	for _, item := range items {
+		switch item.Status {
+		case api.ItemStatusUnread:
+			unread = append(unread, item)
+		case api.ItemStatusArchived:
+			archived = append(archived, item)
+		}
+	}
+
+	if _, err := io.WriteString(w, bookmarksHeader); err != nil {
+		return err
+	}
+
+	for _, folder := range []struct {
+		name  string
+		items []api.Item
+	}{
+		{"Unread", unread},
+		{"Archive", archived},
+	} {
+		if _, err := fmt.Fprintf(w, "    <DT><H3>%s</H3>\n    <DL><p>\n", folder.name); err != nil {
+			return err
+		}
+		for _, item := range folder.items {
+			if err := writeBookmark(w, item, cleanURLs); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, "    </DL><p>\n"); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, bookmarksFooter)
+	return err
+}
+
+func writeBookmark(w io.Writer, item api.Item, cleanURL bool) error {
+	link := item.URL()
+	if cleanURL {
+		link = item.CleanURL()
+	}
+
+	attrs := fmt.Sprintf(`HREF="%s"`, html.EscapeString(link))
+	if added := item.TimeAdded.Time(); !added.IsZero() {
+		attrs += fmt.Sprintf(` ADD_DATE="%d"`, added.Unix())
+	}
+	if tags := item.TagNames(); len(tags) > 0 {
+		attrs += fmt.Sprintf(` TAGS="%s"`, html.EscapeString(strings.Join(tags, ",")))
+	}
+
+	_, err := fmt.Fprintf(w, "        <DT><A %s>%s</A>\n", attrs, html.EscapeString(item.Title()))
+	return err
+}
+
+var (
+	bookmarkToken = regexp.MustCompile(`(?is)<H3[^>]*>(.*?)</H3>|<DL[^>]*>|</DL>|<A\s([^>]*)>(.*?)</A>`)
+	bookmarkAttr  = regexp.MustCompile(`(?s)([A-Za-z_]+)\s*=\s*"([^"]*)"`)
+)
+
+func commandImport(arguments map[string]interface{}, client *api.Client) error {
+	path, _ := arguments["<file>"].(string)
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	options := parseBookmarks(string(b))
+
+	if dryRun {
+		return printAddActions(options)
+	}
+
+	results, err := client.AddBatch(options)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for i, res := range results {
+		if res == nil {
+			fmt.Fprintf(stdout, "Failed to add %s\n", options[i].URL)
+			failed++
+		}
+	}
+	fmt.Fprintf(stdout, "Added %d items, %d failed\n", len(results)-failed, failed)
+	return nil
+}
+
+// parseBookmarks reads the links of a Netscape bookmark file, tagging each
+// with the names of the folders it is in and its own TAGS. ADD_DATE is kept as
+// the time the item was saved at.
+func parseBookmarks(s string) []*api.AddOption {
+	options := []*api.AddOption{}
+
+	// folders holds the enclosing folder names, and folder the name of the
+	// folder whose list is about to be opened.
+	folders := []string{}
+	folder := ""
+	for _, m := range bookmarkToken.FindAllStringSubmatch(s, -1) {
+		token := strings.ToUpper(m[0])
+		switch {
+		case strings.HasPrefix(token, "<H3"):
+			folder = strings.TrimSpace(html.UnescapeString(m[1]))
+		case strings.HasPrefix(token, "<DL"):
+			folders = append(folders, folder)
+			folder = ""
+		case strings.HasPrefix(token, "</DL"):
+			if len(folders) > 0 {
+				folders = folders[:len(folders)-1]
+			}
+		default:
+			options = append(options, parseBookmark(m[2], m[3], folders))
+		}
+	}
+
+	return options
+}
+
+func parseBookmark(attrs, title string, folders []string) *api.AddOption {
+	option := &api.AddOption{
+		Title: strings.TrimSpace(html.UnescapeString(title)),
+	}
+
+	tags := []string{}
+	for _, folder := range folders {
+		if folder != "" {
+			tags = append(tags, folder)
+		}
+	}
+
+	for _, attr := range bookmarkAttr.FindAllStringSubmatch(attrs, -1) {
+		value := html.UnescapeString(attr[2])
+		switch strings.ToUpper(attr[1]) {
+		case "HREF":
+			option.URL = value
+		case "ADD_DATE":
+			if t, err := strconv.ParseInt(value, 10, 64); err == nil {
+				option.Time = t
+			}
+		case "TAGS":
+			tags = append(tags, splitTags(value)...)
+		}
+	}
+
+	// Pocket separates tags with commas and cannot escape them.
+	for i, tag := range tags {
+		tags[i] = strings.Join(strings.Fields(strings.Replace(tag, ",", " ", -1)), " ")
+	}
+	option.TagList = tags
+
+	return option
+}
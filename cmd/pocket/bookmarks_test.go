@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bvp/go-pocket/api"
+	. "github.com/onsi/gomega"
+)
+
+func TestCommandExport(t *testing.T) {
+	RegisterTestingT(t)
+
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		var options map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&options)
+		Expect(options["state"]).To(Equal("all"))
+		Expect(options["detailType"]).To(Equal("complete"))
+		if options["offset"] != nil {
+			w.Write([]byte(`{"list":{},"status":2}`))
+			return
+		}
+		w.Write([]byte(`{"list":{
+			"1":{"item_id":"1","given_title":"First & best","given_url":"https://example.com/1?a=1&b=2","status":"0","time_added":"1577836800","tags":{"go":{"tag":"go"},"cli":{"tag":"cli"}}},
+			"2":{"item_id":"2","resolved_title":"Second","resolved_url":"https://example.com/2","status":"1","sort_id":1}
+		},"status":1}`))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"export", "--html"})
+	Expect(err).To(BeNil())
+
+	Expect(commandExport(arguments, client)).To(Succeed())
+
+	Expect(out.String()).To(Equal(`<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Pocket</TITLE>
+<H1>Pocket</H1>
+<DL><p>
+    <DT><H3>Unread</H3>
+    <DL><p>
+        <DT><A HREF="https://example.com/1?a=1&amp;b=2" ADD_DATE="1577836800" TAGS="cli,go">First &amp; best</A>
+    </DL><p>
+    <DT><H3>Archive</H3>
+    <DL><p>
+        <DT><A HREF="https://example.com/2">Second</A>
+    </DL><p>
+</DL><p>
+`))
+}
+
+func TestCommandExportCleanURLs(t *testing.T) {
+	RegisterTestingT(t)
+
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		var options map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&options)
+		if options["offset"] != nil {
+			w.Write([]byte(`{"list":{},"status":2}`))
+			return
+		}
+		w.Write([]byte(`{"list":{
+			"1":{"item_id":"1","given_title":"First","given_url":"https://example.com/1?id=7&utm_source=feed&fbclid=abc","status":"0"}
+		},"status":1}`))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"export", "--html"})
+	Expect(err).To(BeNil())
+	Expect(commandExport(arguments, client)).To(Succeed())
+	Expect(out.String()).To(ContainSubstring(`HREF="https://example.com/1?id=7&amp;utm_source=feed&amp;fbclid=abc"`))
+
+	out.Reset()
+	arguments, err = parseArguments([]string{"export", "--html", "--clean-urls"})
+	Expect(err).To(BeNil())
+	Expect(commandExport(arguments, client)).To(Succeed())
+	Expect(out.String()).To(ContainSubstring(`HREF="https://example.com/1?id=7"`))
+}
+
+const testBookmarks = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+    <DT><H3 ADD_DATE="1577836800">Toolbar</H3>
+    <DL><p>
+        <DT><A HREF="https://example.com/1?a=1&amp;b=2" ADD_DATE="1577836800">First &amp; best</A>
+        <DT><H3>Go, mostly</H3>
+        <DL><p>
+            <DT><A HREF="https://example.com/2" TAGS="cli,tools">Second</A>
+        </DL><p>
+    </DL><p>
+    <DT><A HREF="javascript:alert(1)">Bookmarklet</A>
+</DL><p>
+`
+
+func TestParseBookmarks(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(parseBookmarks(testBookmarks)).To(Equal([]*api.AddOption{
+		{URL: "https://example.com/1?a=1&b=2", Title: "First & best", TagList: []string{"Toolbar"}, Time: 1577836800},
+		{URL: "https://example.com/2", Title: "Second", TagList: []string{"Toolbar", "Go mostly", "cli", "tools"}},
+		{URL: "javascript:alert(1)", Title: "Bookmarklet", TagList: []string{}},
+	}))
+}
+
+func TestCommandImport(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "bookmarks.html")
+	Expect(ioutil.WriteFile(path, []byte(testBookmarks), 0600)).To(Succeed())
+
+	var actions []*api.Action
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Actions []*api.Action `json:"actions"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		actions = body.Actions
+		w.Write([]byte(`{"action_results":[{"item_id":"1"},false],"status":1}`))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"import", path})
+	Expect(err).To(BeNil())
+
+	Expect(commandImport(arguments, client)).To(Succeed())
+	Expect(actions).To(HaveLen(2))
+	Expect(actions[0].Time).To(Equal(int64(1577836800)))
+	Expect(actions[1].Tags).To(Equal("Toolbar,Go mostly,cli,tools"))
+	Expect(out.String()).To(Equal(`Failed to add https://example.com/2
+Failed to add javascript:alert(1)
+Added 1 items, 2 failed
+`))
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/bvp/go-pocket/api"
+)
+
+// commandDedupe keeps the oldest item of each URL saved more than once, and
+// archives the others, or deletes them with --delete.
+func commandDedupe(arguments map[string]interface{}, client *api.Client) error {
+	archive := true
+	if del, ok := arguments["--delete"].(bool); ok && del {
+		archive = false
+	}
+
+	duplicates, err := client.FindDuplicates()
+	if err != nil {
+		return err
+	}
+
+	urls := make([]string, 0, len(duplicates))
+	for url := range duplicates {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	actions := []*api.Action{}
+	for _, url := range urls {
+		items := duplicates[url]
+		fmt.Fprintf(stdout, "Keeping %d %s\n", items[0].ItemID, url)
+		for _, item := range items[1:] {
+			switch {
+			case !archive:
+				actions = append(actions, api.NewDeleteAction(int(item.ItemID)))
+			case item.Status != api.ItemStatusArchived:
+				actions = append(actions, api.NewArchiveAction(int(item.ItemID)))
+			}
+		}
+	}
+
+	if len(actions) == 0 {
+		fmt.Fprintln(stdout, "No duplicates to remove")
+		return nil
+	}
+
+	if archive {
+		return performActions(client, actions, "Archived")
+	}
+	return performActions(client, actions, "Deleted")
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bvp/go-pocket/api"
+	"github.com/bvp/go-pocket/api/apitest"
+	"github.com/docopt/docopt-go"
+	. "github.com/onsi/gomega"
+)
+
+func dedupeServer() *apitest.Server {
+	server := apitest.NewServer()
+	_, err := server.Client().ModifyBatch(
+		addAction(&api.AddOption{URL: "https://example.com/article?utm_source=feed", Time: 1577836900}),
+		addAction(&api.AddOption{URL: "https://example.com/article/", Time: 1577836800}),
+		addAction(&api.AddOption{URL: "https://example.com/other"}),
+	)
+	Expect(err).To(BeNil())
+	return server
+}
+
+func TestCommandDedupe(t *testing.T) {
+	RegisterTestingT(t)
+
+	server := dedupeServer()
+	defer server.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"dedupe"})
+	Expect(err).To(BeNil())
+
+	Expect(commandDedupe(arguments, server.Client())).To(Succeed())
+	Expect(out.String()).To(Equal("Keeping 2 https://example.com/article\nArchived 1\n"))
+
+	items := server.Items()
+	Expect(items).To(HaveLen(3))
+	Expect(items[0].Status).To(Equal(api.ItemStatus(api.ItemStatusArchived)))
+	Expect(items[1].Status).To(Equal(api.ItemStatusUnread))
+	Expect(items[2].Status).To(Equal(api.ItemStatusUnread))
+
+	// The archived duplicate is left alone the next time.
+	out.Reset()
+	Expect(commandDedupe(arguments, server.Client())).To(Succeed())
+	Expect(out.String()).To(Equal("Keeping 2 https://example.com/article\nNo duplicates to remove\n"))
+}
+
+func TestCommandDedupeDelete(t *testing.T) {
+	RegisterTestingT(t)
+
+	server := dedupeServer()
+	defer server.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"dedupe", "--delete"})
+	Expect(err).To(BeNil())
+
+	Expect(commandDedupe(arguments, server.Client())).To(Succeed())
+	Expect(out.String()).To(Equal("Keeping 2 https://example.com/article\nDeleted 1\n"))
+
+	items := server.Items()
+	Expect(items).To(HaveLen(2))
+	Expect(items[0].ItemID).To(Equal(int64(2)))
+	Expect(items[1].ItemID).To(Equal(int64(3)))
+
+	_, err = docopt.Parse(fmt.Sprintf(usage, getFields()), []string{"dedupe", "--archive", "--delete"}, true, version, false, false)
+	Expect(err).NotTo(BeNil())
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"text/template"
+	"time"
+
+	"github.com/bvp/go-pocket/api"
+)
+
+// templateFuncs are the functions available to --format templates, on top of
+// the built-in ones.
+var templateFuncs = template.FuncMap{
+	"truncate": truncate,
+	"reltime":  reltime,
+	"domain":   domain,
+}
+
+// formatPresets are the templates --format accepts by name.
+var formatPresets = map[string]*template.Template{
+	"oneline":  defaultItemTemplate,
+	"detailed": detailedItemTemplate,
+	"tsv": template.Must(template.New("item").Parse(
+		"{{.ItemID}}\t{{.Title}}\t{{.URL}}",
+	)),
+}
+
+// parseItemTemplate returns the preset named by a --format value, or parses it
+// as a template when it is not the name of one.
+func parseItemTemplate(text string) (*template.Template, error) {
+	if preset, ok := formatPresets[text]; ok {
+		return preset, nil
+	}
+	return template.New("item").Funcs(templateFuncs).Parse(text)
+}
+
+// truncate shortens s to at most n characters, ending it with an ellipsis
+// when anything was cut.
+func truncate(n int, s string) string {
+	runes := []rune(s)
+	if n < 0 || len(runes) <= n {
+		return s
+	}
+	if n == 0 {
+		return ""
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// reltime describes how long ago t was, like "3 days ago". It is empty for a
+// zero time, which Pocket uses for times which are not set.
+func reltime(t api.Time) string {
+	if t.Time().IsZero() {
+		return ""
+	}
+	return relativeTime(time.Since(t.Time()))
+}
+
+func relativeTime(d time.Duration) string {
+	const day = 24 * time.Hour
+
+	units := []struct {
+		name string
+		size time.Duration
+	}{
+		{"year", 365 * day},
+		{"month", 30 * day},
+		{"day", day},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+	}
+	for _, unit := range units {
+		if n := int(d / unit.size); n > 0 {
+			if n == 1 {
+				return fmt.Sprintf("1 %s ago", unit.name)
+			}
+			return fmt.Sprintf("%d %ss ago", n, unit.name)
+		}
+	}
+	return "just now"
+}
+
+// domain returns the host name of rawURL, or an empty string when it cannot be
+// parsed.
+func domain(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
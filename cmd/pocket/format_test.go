@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/bvp/go-pocket/api"
+	. "github.com/onsi/gomega"
+)
+
+func renderItem(format string, item api.Item) string {
+	tmpl, err := parseItemTemplate(format)
+	Expect(err).To(BeNil())
+
+	out := &bytes.Buffer{}
+	Expect(tmpl.Execute(out, item)).To(Succeed())
+	return out.String()
+}
+
+func TestTemplateTruncate(t *testing.T) {
+	RegisterTestingT(t)
+
+	item := api.Item{GivenTitle: "Learning Go in a weekend"}
+	Expect(renderItem(`{{.Title | truncate 11}}`, item)).To(Equal("Learning G…"))
+	Expect(renderItem(`{{.Title | truncate 40}}`, item)).To(Equal("Learning Go in a weekend"))
+
+	item.GivenTitle = "Ünïcödé"
+	Expect(renderItem(`{{.Title | truncate 4}}`, item)).To(Equal("Ünï…"))
+}
+
+func TestTemplateReltime(t *testing.T) {
+	RegisterTestingT(t)
+
+	item := api.Item{TimeAdded: api.Time(time.Now().Add(-3*24*time.Hour - time.Minute))}
+	Expect(renderItem(`{{.TimeAdded | reltime}}`, item)).To(Equal("3 days ago"))
+
+	item.TimeAdded = api.Time(time.Now().Add(-time.Hour - time.Minute))
+	Expect(renderItem(`{{.TimeAdded | reltime}}`, item)).To(Equal("1 hour ago"))
+
+	item.TimeAdded = api.Time(time.Now())
+	Expect(renderItem(`{{.TimeAdded | reltime}}`, item)).To(Equal("just now"))
+
+	item.TimeAdded = api.Time(time.Time{})
+	Expect(renderItem(`{{.TimeAdded | reltime}}`, item)).To(Equal(""))
+}
+
+func TestTemplateDomain(t *testing.T) {
+	RegisterTestingT(t)
+
+	item := api.Item{GivenTitle: "First", GivenURL: "https://www.example.com:8080/path?q=1"}
+	Expect(renderItem(`{{.Title}} ({{.URL | domain}})`, item)).To(Equal("First (www.example.com)"))
+}
+
+func TestFormatPresets(t *testing.T) {
+	RegisterTestingT(t)
+
+	tmpl, err := parseItemTemplate("oneline")
+	Expect(err).To(BeNil())
+	Expect(tmpl).To(BeIdenticalTo(defaultItemTemplate))
+
+	tmpl, err = parseItemTemplate("detailed")
+	Expect(err).To(BeNil())
+	Expect(tmpl).To(BeIdenticalTo(detailedItemTemplate))
+
+	item := api.Item{ItemID: 1, GivenTitle: "First", GivenURL: "https://example.com/1"}
+	Expect(renderItem("tsv", item)).To(Equal("1\tFirst\thttps://example.com/1"))
+
+	// Anything else is a template, even if it looks like a name.
+	Expect(renderItem("oneline2", item)).To(Equal("oneline2"))
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+
+	"github.com/bvp/go-pocket/api"
+	"github.com/bvp/go-pocket/auth"
+)
+
+// authOptions configure how the access token is obtained when logging in.
+type authOptions struct {
+	// listen is the local address of the server waiting for Pocket's
+	// redirect. Empty picks a free port on localhost.
+	listen string
+	// redirectURL is the URL Pocket redirects the browser to, when the
+	// server is reached through a tunnel or proxy rather than directly.
+	// Empty uses the server's own URL.
+	redirectURL string
+	// manual skips the server: the user presses Enter once the app is
+	// authorized instead.
+	manual bool
+	// open opens the authorization URL in the browser, on top of printing
+	// it. It does not apply to manual logins.
+	open bool
+}
+
+func parseAuthOptions(arguments map[string]interface{}) (*authOptions, error) {
+	options := &authOptions{}
+
+	if listen, ok := arguments["--auth-listen"].(string); ok {
+		options.listen = listen
+	}
+
+	if redirectURL, ok := arguments["--auth-redirect-url"].(string); ok {
+		u, err := url.Parse(redirectURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("--auth-redirect-url must be an http or https URL, got %q", redirectURL)
+		}
+		options.redirectURL = redirectURL
+	}
+
+	options.manual, _ = arguments["--manual-auth"].(bool)
+
+	noOpen, _ := arguments["--no-open"].(bool)
+	options.open = !noOpen
+
+	return options, nil
+}
+
+// obtainAccessToken logs in by printing the authorization URL, and waiting
+// for Pocket to redirect the browser back once the user authorized the app.
+func obtainAccessToken(consumerKey string, options *authOptions) (*auth.Authorization, error) {
+	if options.manual {
+		return obtainAccessTokenManually(consumerKey, options)
+	}
+
+	state, err := auth.NewState()
+	if err != nil {
+		return nil, err
+	}
+
+	listen := options.listen
+	if listen == "" {
+		listen = "127.0.0.1:0"
+	}
+	l, err := net.Listen("tcp", listen)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan struct{}, 1)
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/favicon.ico" {
+				http.Error(w, "Not Found", 404)
+				return
+			}
+
+			if !auth.VerifyState(req.URL, state) {
+				http.Error(w, "Bad Request", 400)
+				return
+			}
+
+			w.Header().Set("Content-Type", "text/plain")
+			fmt.Fprintln(w, "Authorized.")
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}),
+	}
+	go server.Serve(l)
+	defer server.Close()
+
+	redirectURL := options.redirectURL
+	if redirectURL == "" {
+		redirectURL = "http://" + l.Addr().String()
+	}
+
+	requestToken, err := auth.ObtainRequestToken(consumerKey, redirectURL)
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := auth.GenerateAuthorizationURLWithState(requestToken, redirectURL, state)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(stdout, url)
+	if options.open {
+		if err := openBrowser(url); err != nil {
+			logger.Printf("Can't open the browser, open the URL above instead: %v", err)
+		}
+	}
+
+	<-ch
+
+	return auth.ObtainAccessToken(consumerKey, requestToken)
+}
+
+// obtainAccessTokenManually logs in without waiting for a redirect, which
+// cannot reach a headless machine: Pocket lets the request token be exchanged
+// once the user authorized it, which they confirm by pressing Enter.
+func obtainAccessTokenManually(consumerKey string, options *authOptions) (*auth.Authorization, error) {
+	// Pocket requires a redirect URL, where the browser ends up afterwards.
+	redirectURL := options.redirectURL
+	if redirectURL == "" {
+		redirectURL = api.Origin
+	}
+
+	requestToken, err := auth.ObtainRequestToken(consumerKey, redirectURL)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintln(stdout, auth.GenerateAuthorizationURL(requestToken, redirectURL))
+	fmt.Fprint(stderr, "Open the URL above in any browser, authorize pocket, then press Enter: ")
+	if _, err := bufio.NewReader(stdin).ReadString('\n'); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return auth.ObtainAccessToken(consumerKey, requestToken)
+}
+
+// openBrowser opens url in the default browser.
+var openBrowser = func(url string) error {
+	name, args := browserCommand(runtime.GOOS, url)
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return err
+	}
+	return exec.Command(path, args...).Start()
+}
+
+// browserCommand returns the command which opens url in the default browser
+// on goos.
+func browserCommand(goos, url string) (string, []string) {
+	switch goos {
+	case "darwin":
+		return "open", []string{url}
+	case "windows":
+		return "rundll32", []string{"url.dll,FileProtocolHandler", url}
+	default:
+		return "xdg-open", []string{url}
+	}
+}
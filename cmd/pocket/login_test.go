@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bvp/go-pocket/api"
+	. "github.com/onsi/gomega"
+)
+
+// fakeOAuthServer serves Pocket's OAuth endpoints, handing out an access token
+// for the request token it gave. The redirect URI of the request is stored in
+// redirectURI.
+func fakeOAuthServer(redirectURI *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		switch r.URL.Path {
+		case "/v3/oauth/request":
+			*redirectURI = body["redirect_uri"]
+			w.Write([]byte(`{"code":"request-code"}`))
+		case "/v3/oauth/authorize":
+			if body["code"] != "request-code" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"access_token":"access-token","username":"user"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+// freeAddress returns a local address nothing listens on.
+func freeAddress() string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).To(BeNil())
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// readAuthorizationURL returns the redirect URL of the authorization URL
+// printed to r.
+func readAuthorizationURL(r io.Reader) (*url.URL, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	authorization, err := url.Parse(strings.TrimSpace(line))
+	if err != nil {
+		return nil, err
+	}
+	return url.Parse(authorization.Query().Get("redirect_uri"))
+}
+
+func TestObtainAccessTokenExternalRedirectURL(t *testing.T) {
+	RegisterTestingT(t)
+
+	var redirectURI string
+	ts := fakeOAuthServer(&redirectURI)
+	defer ts.Close()
+	defer func(origin string) { api.Origin = origin }(api.Origin)
+	api.Origin = ts.URL
+
+	pr, pw := io.Pipe()
+	stdout = pw
+	defer resetStdout()
+
+	local := freeAddress()
+	arguments, err := parseArguments([]string{"list", "--auth-listen", local, "--auth-redirect-url", "https://tunnel.example.com/pocket", "--no-open"})
+	Expect(err).To(BeNil())
+	options, err := parseAuthOptions(arguments)
+	Expect(err).To(BeNil())
+
+	// The browser is sent to the external URL, which the tunnel forwards to
+	// the local server.
+	type callback struct {
+		redirect *url.URL
+		status   int
+		err      error
+	}
+	done := make(chan callback)
+	go func() {
+		redirect, err := readAuthorizationURL(pr)
+		if err != nil {
+			done <- callback{err: err}
+			return
+		}
+		resp, err := http.Get("http://" + local + redirect.RequestURI())
+		if err != nil {
+			done <- callback{err: err}
+			return
+		}
+		resp.Body.Close()
+		done <- callback{redirect: redirect, status: resp.StatusCode}
+	}()
+
+	accessToken, err := obtainAccessToken("consumer", options)
+	Expect(err).To(BeNil())
+	Expect(accessToken.AccessToken).To(Equal("access-token"))
+	Expect(redirectURI).To(Equal("https://tunnel.example.com/pocket"))
+
+	browser := <-done
+	Expect(browser.err).To(BeNil())
+	Expect(browser.redirect.Host).To(Equal("tunnel.example.com"))
+	Expect(browser.redirect.Path).To(Equal("/pocket"))
+	Expect(browser.status).To(Equal(http.StatusOK))
+}
+
+func TestParseAuthOptionsInvalidRedirectURL(t *testing.T) {
+	RegisterTestingT(t)
+
+	arguments, err := parseArguments([]string{"list", "--auth-redirect-url", "tunnel.example.com"})
+	Expect(err).To(BeNil())
+	_, err = parseAuthOptions(arguments)
+	Expect(err).To(MatchError(ContainSubstring("--auth-redirect-url must be an http or https URL")))
+}
+
+func TestObtainAccessTokenManually(t *testing.T) {
+	RegisterTestingT(t)
+
+	var redirectURI string
+	ts := fakeOAuthServer(&redirectURI)
+	defer ts.Close()
+	defer func(origin string) { api.Origin = origin }(api.Origin)
+	api.Origin = ts.URL
+
+	out := captureStdout()
+	defer resetStdout()
+	defer func() { stdin = os.Stdin }()
+	defer func() { stderr = os.Stderr }()
+	stdin = strings.NewReader("\n")
+	stderr = ioutil.Discard
+
+	// Nothing may listen on the address, as no server is started.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).To(BeNil())
+	defer l.Close()
+
+	arguments, err := parseArguments([]string{"list", "--manual-auth", "--auth-listen", l.Addr().String()})
+	Expect(err).To(BeNil())
+	options, err := parseAuthOptions(arguments)
+	Expect(err).To(BeNil())
+
+	accessToken, err := obtainAccessToken("consumer", options)
+	Expect(err).To(BeNil())
+	Expect(accessToken.AccessToken).To(Equal("access-token"))
+	Expect(redirectURI).To(Equal(ts.URL))
+
+	redirect, err := readAuthorizationURL(out)
+	Expect(err).To(BeNil())
+	Expect(redirect.String()).To(Equal(ts.URL))
+}
+
+func TestObtainAccessTokenOpensBrowser(t *testing.T) {
+	RegisterTestingT(t)
+
+	var redirectURI string
+	ts := fakeOAuthServer(&redirectURI)
+	defer ts.Close()
+	defer func(origin string) { api.Origin = origin }(api.Origin)
+	api.Origin = ts.URL
+
+	captureStdout()
+	defer resetStdout()
+	defer func(open func(string) error) { openBrowser = open }(openBrowser)
+
+	// The browser follows the authorization URL, and is redirected back.
+	opened := []string{}
+	openBrowser = func(rawURL string) error {
+		opened = append(opened, rawURL)
+		authorization, err := url.Parse(rawURL)
+		if err != nil {
+			return err
+		}
+		go func() {
+			resp, err := http.Get(authorization.Query().Get("redirect_uri"))
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+		return nil
+	}
+
+	arguments, err := parseArguments([]string{"list"})
+	Expect(err).To(BeNil())
+	options, err := parseAuthOptions(arguments)
+	Expect(err).To(BeNil())
+
+	accessToken, err := obtainAccessToken("consumer", options)
+	Expect(err).To(BeNil())
+	Expect(accessToken.AccessToken).To(Equal("access-token"))
+	Expect(opened).To(HaveLen(1))
+	Expect(opened[0]).To(HavePrefix(ts.URL + "/auth/authorize?"))
+
+	arguments, err = parseArguments([]string{"list", "--no-open"})
+	Expect(err).To(BeNil())
+	options, err = parseAuthOptions(arguments)
+	Expect(err).To(BeNil())
+	Expect(options.open).To(BeFalse())
+}
+
+func TestBrowserCommand(t *testing.T) {
+	RegisterTestingT(t)
+
+	for _, test := range []struct {
+		goos string
+		name string
+		args []string
+	}{
+		{"darwin", "open", []string{"https://example.com/?a=1&b=2"}},
+		{"linux", "xdg-open", []string{"https://example.com/?a=1&b=2"}},
+		{"freebsd", "xdg-open", []string{"https://example.com/?a=1&b=2"}},
+		{"windows", "rundll32", []string{"url.dll,FileProtocolHandler", "https://example.com/?a=1&b=2"}},
+	} {
+		name, args := browserCommand(test.goos, "https://example.com/?a=1&b=2")
+		Expect(name).To(Equal(test.name), test.goos)
+		Expect(args).To(Equal(test.args), test.goos)
+	}
+}
@@ -3,24 +3,24 @@ package main
 import (
 	"bufio"
 	"bytes"
-	"crypto/sha256"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
-	"net/http/httptest"
 	"os"
-	"os/exec"
 	"os/user"
 	"path/filepath"
 	"reflect"
-	"regexp"
 	"runtime"
 	"sort"
 	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/docopt/docopt-go"
 	"github.com/bvp/go-pocket/api"
@@ -29,36 +29,81 @@ import (
 
 var version = "0.1"
 
-const maxFilename = 127
-const maxTitle = maxFilename - len(`.webloc`)
-
 var defaultItemTemplate = template.Must(template.New("item").Parse(
 	`[{{.ItemID | printf "%9d"}}] {{.Title}} <{{.URL}}>`,
 ))
 
-var spotlightItemTemplate = template.Must(template.New("item").Parse(`<?xml version="1.0" encoding="UTF-8"?>
-<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
-<plist version="1.0">
-<dict>
-    <key>URL</key>
-    <string>{{- .URL | html -}}</string>
-</dict>
-</plist>`,
+// colorItemTemplate is defaultItemTemplate for terminals, with the item id
+// dimmed and the titles of favorites in yellow.
+var colorItemTemplate = template.Must(template.New("item").Parse(
+	ansiDim + `[{{.ItemID | printf "%9d"}}]` + ansiReset +
+		` {{if eq .Favorite 1}}` + ansiYellow + `{{.Title}}` + ansiReset + `{{else}}{{.Title}}{{end}} <{{.URL}}>`,
+))
+
+var detailedItemTemplate = template.Must(template.New("item").Parse(
+	`Item ID:  {{.ItemID}}
+Title:    {{.Title}}
+URL:      {{.URL}}
+Status:   {{if eq .Status 1}}archived{{else}}unread{{end}}
+Favorite: {{if eq .Favorite 1}}yes{{else}}no{{end}}
+Tags:     {{range $i, $tag := .TagNames}}{{if $i}}, {{end}}{{$tag}}{{end}}
+Words:    {{.WordCount}}
+Added:    {{if not .TimeAdded.Time.IsZero}}{{.TimeAdded.Time.UTC.Format "2006-01-02 15:04:05"}}{{end}}
+Excerpt:  {{.Excerpt}}`,
 ))
 
 var configDir string
 
-func init() {
-	usr, err := user.Current()
-	if err != nil {
-		panic(err)
+// dryRun makes commands print the actions they would send to Pocket instead
+// of sending them.
+var dryRun bool
+
+// stdout is where commands write their output.
+var stdout io.Writer = os.Stdout
+
+// stdin is where commands read user input from.
+var stdin io.Reader = os.Stdin
+
+// stderr is where errors are reported.
+var stderr io.Writer = os.Stderr
+
+// exit ends the program with the given status.
+var exit = os.Exit
+
+// logger reports what the program is doing, apart from errors. It is silenced
+// by --quiet.
+var logger = log.New(os.Stderr, "", log.LstdFlags)
+
+// setupConfigDir resolves the config directory from the --config-dir flag,
+// the POCKET_CONFIG_DIR environment variable or the default under the home
+// directory, in that order of precedence, and creates it. With --profile, the
+// profile's subdirectory of it is used instead.
+func setupConfigDir(arguments map[string]interface{}) (string, error) {
+	dir, _ := arguments["--config-dir"].(string)
+	if dir == "" {
+		dir = os.Getenv("POCKET_CONFIG_DIR")
+	}
+	if dir == "" {
+		usr, err := user.Current()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(usr.HomeDir, ".config", "pocket")
 	}
 
-	configDir = filepath.Join(usr.HomeDir, ".config", "pocket")
-	err = os.MkdirAll(configDir, 0777)
+	if profile, ok := arguments["--profile"].(string); ok {
+		if profile == "" || profile == "." || profile == ".." || strings.ContainsAny(profile, `/\`) {
+			return "", fmt.Errorf("invalid profile name %q", profile)
+		}
+		dir = filepath.Join(dir, profile)
+	}
+
+	err := os.MkdirAll(dir, 0777)
 	if err != nil {
-		panic(err)
+		return "", err
 	}
+
+	return dir, nil
 }
 
 func getFields() string {
@@ -70,77 +115,382 @@ func getFields() string {
 	return strings.Join(ret, ", ")
 }
 
-func main() {
-	usage := `A Pocket <getpocket.com> client.
+const usage = `A Pocket <getpocket.com> client.
 
 Usage:
-  pocket list [--format=<template>] [--domain=<domain>] [--tag=<tag>] [--search=<query>]
-  pocket archive <item-id>
-  pocket add <url> [--title=<title>] [--tags=<tags>]
-  pocket spotlight [--indexdir=<dir>]
+  pocket list [--format=<template> | --json | --jsonl | --csv] [--domain=<domain> | --domains=<domains>] [--tag=<tag> | --untagged] [--search=<query>] [--count=<n>] [--offset=<n>] [--state=<state>] [--favorite] [--sort=<order>] [--tags-all=<tags>] [--min-words=<n>] [--offline] [options]
+  pocket count [--domain=<domain>] [--tag=<tag>] [--search=<query>] [--state=<state>] [--favorite] [options]
+  pocket search <query> [--state=<state>] [--tag=<tag>] [--count=<n>] [options]
+  pocket get <item-id> [--format=<template> | --json] [options]
+  pocket archive <item-ids>... [options]
+  pocket unarchive <item-id> [options]
+  pocket favorite <item-id> [options]
+  pocket unfavorite <item-id> [options]
+  pocket delete <item-ids>... [--force] [options]
+  pocket tag (add | remove) <item-id> <tags> [options]
+  pocket tags [--sort=<order>] [options]
+  pocket rename-tag <old> <new> [options]
+  pocket dedupe [--archive | --delete] [options]
+  pocket prune [--archive | --delete] [--workers=<n>] [options]
+  pocket add <url> [--title=<title>] [--tags=<tags>] [options]
+  pocket export --html [--clean-urls] [options]
+  pocket import <file> [options]
+  pocket backup [options]
+  pocket restore <file> [options]
+  pocket spotlight [--indexdir=<dir>] [--incremental] [--template-file=<path>] [--no-index] [options]
 
 Options for list:
-  -f, --format <template> A Go template to show items.
+  -f, --format <template> oneline, detailed, tsv or a Go template to show
+                          items. On top of the built-in functions, templates
+                          can use truncate <n>, reltime and domain, as in
+                          '{{.Title | truncate 40}} ({{.URL | domain}},
+                          {{.TimeAdded | reltime}})'.
+  --json                  Print the items as a JSON array.
+  --jsonl                 Print each item as JSON on a line of its own, as
+                          soon as it is retrieved.
+  --csv                   Print the items as CSV.
   -d, --domain <domain>   Filter items by its domain when listing.
+  --domains <domains>     Only show items on any of a comma-separated list of
+                          domains, ignoring a leading www.
   -s, --search <query>    Search query when listing.
   -t, --tag <tag>         Filter items by a tag when listing.
+  --tags-all <tags>       Only show items having all of a comma-separated
+                          list of tags.
+  --untagged              Only show items without any tags.
+  --min-words <n>         Only show items of more than n words.
+  --count <n>             Show at most n items.
+  --offset <n>            Skip the first n items.
+  --state <state>         Show unread (the default), archive or all items.
+  --favorite              Only show favorited items.
+  --sort <order>          Sort by newest, oldest, title or site.
+  --offline               Show the items of the last unfiltered list, saved in
+                          the config directory, without connecting to Pocket.
+                          Only the filters pocket applies itself, --tags-all,
+                          --untagged, --domains and --min-words, can be used.
+
+The count command takes the same filters as list.
+
+Use - as the item id of archive or delete to read newline-separated ids from
+stdin.
+
+Options for delete:
+  --force                 Delete without asking for confirmation.
+
+Options for dedupe:
+  --archive               Archive the duplicates, which is the default.
+  --delete                Permanently delete the duplicates instead.
+
+Options for prune:
+  --archive               Archive the unread items whose page is gone, which
+                          is the default.
+  --delete                Permanently delete every item whose page is gone
+                          instead.
+  --workers <n>           How many links to check at once (default 4).
+
+Options for tags:
+  --sort <order>          Sort by count (the default) or name.
 
 Options for add:
   --title <title>         A manually specified title for the article
   --tags <tags>           A comma-separated list of tags
 
+Options for export:
+  --clean-urls            Remove tracking parameters, like utm_source or
+                          fbclid, from the links.
+
 Options for spotlight:
   --indexdir <dir>        Where the spotlight metadata should be saved.
                           NOTE: Must not contain any hidden ('.' prefixed) directories.
                           CAUTION: Everything under it will be deleted.
+  --incremental           Only update the items changed since the last run.
+  --template-file <path>  A Go template file to write each .webloc with, in
+                          place of the built-in plist.
+  --no-index              Write the .webloc files without running mdimport.
+
+Global options:
+  --config-dir <dir>      Where the consumer key and access token are kept
+                          (default $POCKET_CONFIG_DIR or ~/.config/pocket).
+  --profile <name>        Use the named profile, kept in its own
+                          subdirectory of the config directory.
+  --consumer-key <key>    The consumer key of the app to use, in place of
+                          $POCKET_CONSUMER_KEY or the saved one.
+  -q, --quiet             Only report errors.
+  -v, --verbose           Log every request made to Pocket.
+  -n, --dry-run           Print the actions archive, delete, tag, rename-tag,
+                          import, restore and the like would send, without
+                          sending them.
+  --auth-listen <addr>    The local address to wait for Pocket's redirect on
+                          when logging in (default a free port on localhost).
+  --auth-redirect-url <url>
+                          The URL Pocket redirects the browser to when logging
+                          in, when it reaches the --auth-listen address
+                          through a tunnel or proxy.
+  --manual-auth           Log in without waiting for Pocket's redirect, by
+                          pressing Enter once authorized, as on machines
+                          without a browser.
+  --no-open               Only print the URL to log in at, without opening
+                          it in the browser.
+
 Fields for format template:
    %s
 
 list - Shows your pocket list
-archive - Moves an item to archive
+count - Prints the number of items in your pocket list
+search - Shows the items whose title or URL contains a query, highlighted
+get - Shows the details of an item
+archive - Moves items to archive
+unarchive - Moves an item back to the unread list
+favorite - Marks an item as a favorite
+unfavorite - Removes an item from the favorites
+delete - Permanently deletes items
+tag - Adds or removes a comma-separated list of tags on an item
+tags - Lists all tags with the number of items using them
+rename-tag - Renames a tag on all items
+dedupe - Archives, or deletes with --delete, the items saved again under a URL
+         already in pocket, keeping the oldest
+prune - Archives, or deletes with --delete, the items whose page is gone
 add - Adds a new URL to pocket
+export - Writes all items as an HTML bookmarks file, which browsers import
+import - Adds the links of an HTML bookmarks file, tagged with their folders
+backup - Writes every item, with all of its details, as JSON
+restore - Adds the items of a backup again
 spotlight - On Mac OS X, adds the pocket bookmarks to spotlight index
 `
 
-	u := fmt.Sprintf(usage, getFields())
-	arguments, err := docopt.Parse(u, nil, true, version, false)
+func parseArguments(argv []string) (map[string]interface{}, error) {
+	return docopt.Parse(fmt.Sprintf(usage, getFields()), argv, true, version, false)
+}
+
+func main() {
+	arguments, err := parseArguments(os.Args[1:])
 	if err != nil {
-		panic(err)
+		die(err)
 	}
 
-	consumerKey := getConsumerKey()
+	configDir, err = setupConfigDir(arguments)
+	if err != nil {
+		die(err)
+	}
 
-	accessToken, err := restoreAccessToken(consumerKey)
+	verbose, err := setupLogging(arguments)
 	if err != nil {
-		panic(err)
+		die(err)
 	}
 
-	client := api.NewClient(consumerKey, accessToken.AccessToken)
+	dryRun, _ = arguments["--dry-run"].(bool)
 
+	consumerKey, err := getConsumerKey(arguments)
+	if err != nil {
+		die(err)
+	}
+
+	authOptions, err := parseAuthOptions(arguments)
+	if err != nil {
+		die(err)
+	}
+
+	accessToken, err := restoreAccessToken(consumerKey, authOptions)
+	if err != nil {
+		die(err)
+	}
+
+	var hc *http.Client
+	if verbose {
+		hc = &http.Client{Transport: loggingTransport{http.DefaultTransport}}
+	}
+	client := api.NewClientWithHTTP(consumerKey, accessToken.AccessToken, hc)
+
+	// A token from the environment is left for the caller to replace, as one
+	// saved to auth.json would not be used while the variable is set.
+	var reauthorizeFunc func() (*auth.Authorization, error)
+	if os.Getenv("POCKET_ACCESS_TOKEN") == "" {
+		reauthorizeFunc = func() (*auth.Authorization, error) {
+			return reauthorize(consumerKey, authOptions)
+		}
+	}
+
+	err = runWithReauthorization(arguments, client, reauthorizeFunc)
+	if err != nil {
+		die(err)
+	}
+}
+
+// setupLogging silences logger for --quiet, and reports whether --verbose
+// asks for requests to be logged.
+func setupLogging(arguments map[string]interface{}) (bool, error) {
+	quiet, _ := arguments["--quiet"].(bool)
+	verbose, _ := arguments["--verbose"].(bool)
+	if quiet && verbose {
+		return false, errors.New("--quiet and --verbose cannot be used together")
+	}
+
+	if quiet {
+		logger.SetOutput(ioutil.Discard)
+	}
+
+	return verbose, nil
+}
+
+// loggingTransport logs a summary of each request and its response.
+type loggingTransport struct {
+	next http.RoundTripper
+}
+
+func (t loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		logger.Printf("%s %s: %v (%v)", req.Method, req.URL, err, elapsed)
+		return nil, err
+	}
+
+	logger.Printf("%s %s: %s (%v)", req.Method, req.URL, resp.Status, elapsed)
+	return resp, nil
+}
+
+// runCommand runs the command chosen by arguments.
+func runCommand(arguments map[string]interface{}, client *api.Client) error {
 	if do, ok := arguments["list"].(bool); ok && do {
-		commandList(arguments, client)
-	} else if do, ok := arguments["archive"].(bool); ok && do {
-		commandArchive(arguments, client)
-	} else if do, ok := arguments["add"].(bool); ok && do {
-		commandAdd(arguments, client)
-	} else if do, ok := arguments["spotlight"].(bool); ok && do {
+		return commandList(arguments, client)
+	}
+	if do, ok := arguments["count"].(bool); ok && do {
+		return commandCount(arguments, client)
+	}
+	if do, ok := arguments["search"].(bool); ok && do {
+		return commandSearch(arguments, client)
+	}
+	if do, ok := arguments["get"].(bool); ok && do {
+		return commandGet(arguments, client)
+	}
+	if do, ok := arguments["archive"].(bool); ok && do {
+		return commandArchive(arguments, client)
+	}
+	if do, ok := arguments["unarchive"].(bool); ok && do {
+		return commandUnarchive(arguments, client)
+	}
+	if do, ok := arguments["favorite"].(bool); ok && do {
+		return commandFavorite(arguments, client)
+	}
+	if do, ok := arguments["unfavorite"].(bool); ok && do {
+		return commandFavorite(arguments, client)
+	}
+	if do, ok := arguments["delete"].(bool); ok && do {
+		return commandDelete(arguments, client)
+	}
+	if do, ok := arguments["tag"].(bool); ok && do {
+		return commandTag(arguments, client)
+	}
+	if do, ok := arguments["dedupe"].(bool); ok && do {
+		return commandDedupe(arguments, client)
+	}
+	if do, ok := arguments["prune"].(bool); ok && do {
+		return commandPrune(arguments, client)
+	}
+	if do, ok := arguments["tags"].(bool); ok && do {
+		return commandTags(arguments, client)
+	}
+	if do, ok := arguments["rename-tag"].(bool); ok && do {
+		return commandRenameTag(arguments, client)
+	}
+	if do, ok := arguments["add"].(bool); ok && do {
+		return commandAdd(arguments, client)
+	}
+	if do, ok := arguments["export"].(bool); ok && do {
+		return commandExport(arguments, client)
+	}
+	if do, ok := arguments["import"].(bool); ok && do {
+		return commandImport(arguments, client)
+	}
+	if do, ok := arguments["backup"].(bool); ok && do {
+		return commandBackup(arguments, client)
+	}
+	if do, ok := arguments["restore"].(bool); ok && do {
+		return commandRestore(arguments, client)
+	}
+	if do, ok := arguments["spotlight"].(bool); ok && do {
 		if runtime.GOOS != "darwin" {
-			fmt.Fprintln(os.Stderr, "This command is only meaningful on Mac OS X")
-			os.Exit(1)
+			return errors.New("This command is only meaningful on Mac OS X")
 		}
-		commandSpotlight(arguments, client)
-	} else {
-		panic("Not implemented")
+		return commandSpotlight(arguments, client)
 	}
+
+	return errors.New("Not implemented")
 }
 
-type bySortID []api.Item
+// runWithReauthorization runs the command chosen by arguments, and if Pocket
+// rejects the access token, obtains a new one with reauthorize and runs the
+// command once more. A nil reauthorize means the token came from
+// POCKET_ACCESS_TOKEN, and the error asks for it to be replaced instead.
+func runWithReauthorization(arguments map[string]interface{}, client *api.Client, reauthorize func() (*auth.Authorization, error)) error {
+	// The command may run twice, but stdin can only be read once.
+	arguments, err := readCommandInput(arguments)
+	if err != nil || arguments == nil {
+		return err
+	}
+
+	err = runCommand(arguments, client)
+	if !errors.Is(err, api.ErrUnauthorized) {
+		return err
+	}
+	if reauthorize == nil {
+		return fmt.Errorf("%w; replace the access token in POCKET_ACCESS_TOKEN", err)
+	}
 
-func (s bySortID) Len() int           { return len(s) }
-func (s bySortID) Less(i, j int) bool { return s[i].SortId < s[j].SortId }
-func (s bySortID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+	logger.Println("The access token was rejected, authorizing again")
+
+	accessToken, err := reauthorize()
+	if err != nil {
+		return err
+	}
+	client.AccessToken = accessToken.AccessToken
+
+	return runCommand(arguments, client)
+}
 
-func commandList(arguments map[string]interface{}, client *api.Client) {
+// readCommandInput reads the item ids of archive and delete given as "-" from
+// stdin, and asks for deleting to be confirmed, returning a copy of arguments
+// with the ids in place of "-" and --force set. The returned arguments are
+// nil when deleting is not confirmed.
+func readCommandInput(arguments map[string]interface{}) (map[string]interface{}, error) {
+	archive, _ := arguments["archive"].(bool)
+	del, _ := arguments["delete"].(bool)
+	if !archive && !del {
+		return arguments, nil
+	}
+
+	itemIDs, err := itemIDsArgument(arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	read := map[string]interface{}{}
+	for key, value := range arguments {
+		read[key] = value
+	}
+	itemIDStrings := make([]string, len(itemIDs))
+	for i, itemID := range itemIDs {
+		itemIDStrings[i] = strconv.Itoa(itemID)
+	}
+	read["<item-ids>"] = itemIDStrings
+
+	if del {
+		confirmed, err := confirmDelete(arguments, itemIDs)
+		if err != nil {
+			return nil, err
+		}
+		if !confirmed {
+			fmt.Fprintln(stdout, "Aborted")
+			return nil, nil
+		}
+		read["--force"] = true
+	}
+
+	return read, nil
+}
+
+// listOptions builds the retrieve options from the list command's filters.
+func listOptions(arguments map[string]interface{}) (*api.RetrieveOption, error) {
 	options := &api.RetrieveOption{}
 
 	if domain, ok := arguments["--domain"].(string); ok {
@@ -155,213 +505,781 @@ func commandList(arguments map[string]interface{}, client *api.Client) {
 		options.Tag = tag
 	}
 
-	res, err := client.Retrieve(options)
+	var err error
+	if state, ok := arguments["--state"].(string); ok {
+		if options.State, err = parseState(state); err != nil {
+			return nil, err
+		}
+	}
+
+	if favorite, ok := arguments["--favorite"].(bool); ok && favorite {
+		options.Favorite = api.FavoriteFilterFavorited
+	}
+
+	if order, ok := arguments["--sort"].(string); ok {
+		if options.Sort, err = parseSort(order); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.Count, err = nonNegativeArgument(arguments, "--count"); err != nil {
+		return nil, err
+	}
+
+	if options.Offset, err = nonNegativeArgument(arguments, "--offset"); err != nil {
+		return nil, err
+	}
+
+	return options, nil
+}
+
+// listServerFilters returns the list options given in arguments which Pocket
+// applies, rather than pocket itself.
+func listServerFilters(arguments map[string]interface{}) []string {
+	filters := []string{}
+	for _, name := range []string{"--state", "--tag", "--domain", "--search", "--favorite", "--count", "--offset", "--sort"} {
+		switch value := arguments[name].(type) {
+		case string:
+			filters = append(filters, name)
+		case bool:
+			if value {
+				filters = append(filters, name)
+			}
+		}
+	}
+	return filters
+}
+
+// parseState maps a --state value to the state constant.
+func parseState(s string) (api.State, error) {
+	switch api.State(s) {
+	case api.StateUnread, api.StateArchive, api.StateAll:
+		return api.State(s), nil
+	}
+
+	return "", fmt.Errorf("unknown state %q; valid states are unread, archive and all", s)
+}
+
+// parseSort maps a --sort value to the sort constant.
+func parseSort(s string) (api.Sort, error) {
+	switch api.Sort(s) {
+	case api.SortNewest, api.SortOldest, api.SortTitle, api.SortSite:
+		return api.Sort(s), nil
+	}
+
+	return "", fmt.Errorf("unknown sort order %q; valid orders are newest, oldest, title and site", s)
+}
+
+// nonNegativeArgument parses the named argument as a non-negative integer,
+// returning zero if it was not given.
+func nonNegativeArgument(arguments map[string]interface{}, name string) (int, error) {
+	value, ok := arguments[name].(string)
+	if !ok {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer, got %q", name, value)
+	}
+
+	return n, nil
+}
+
+func commandList(arguments map[string]interface{}, client *api.Client) error {
+	options, err := listOptions(arguments)
+	if err != nil {
+		return err
+	}
+
+	asCSV, _ := arguments["--csv"].(bool)
+	tagsAll, filterTags := arguments["--tags-all"].(string)
+	untagged, _ := arguments["--untagged"].(bool)
+	minWords, err := nonNegativeArgument(arguments, "--min-words")
+	if err != nil {
+		return err
+	}
+	if asCSV || filterTags || untagged || minWords > 0 {
+		// Tags are only included with complete details.
+		options.DetailType = api.DetailTypeComplete
+	}
+
+	// filter applies the filters the API does not support.
+	filter := func(items api.ItemList) api.ItemList {
+		if filterTags {
+			// The API filters by a single tag only.
+			for _, tag := range splitTags(tagsAll) {
+				items = items.WithTag(tag)
+			}
+		}
+		if untagged {
+			items = items.Untagged()
+		}
+		if domains, ok := arguments["--domains"].(string); ok {
+			items = items.WithAnyDomain(splitTags(domains)...)
+		}
+		if minWords > 0 {
+			items = items.WithMinWords(minWords)
+		}
+		return items
+	}
+
+	// Only unfiltered lists are cached, and --offline has no server to
+	// filter the cached list with.
+	serverFilters := listServerFilters(arguments)
+	offline, _ := arguments["--offline"].(bool)
+	if offline && len(serverFilters) > 0 {
+		return fmt.Errorf("--offline cannot be used with %s", strings.Join(serverFilters, ", "))
+	}
+
+	asJSONL, _ := arguments["--jsonl"].(bool)
+	if asJSONL && !offline && options.Count == 0 {
+		// Items are printed page by page as they are retrieved, which
+		// leaves the cached list alone.
+		encoder := json.NewEncoder(stdout)
+		return client.RetrieveEach(options, func(item api.Item) error {
+			for _, item := range filter(api.ItemList{item}) {
+				if err := encoder.Encode(item); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	var res *api.RetrieveResult
+	if offline {
+		res, err = loadListCache()
+	} else {
+		cache := len(serverFilters) == 0
+		if cache {
+			// The cache must hold the tags for the filters of --offline.
+			options.DetailType = api.DetailTypeComplete
+		}
+		res, err = client.Retrieve(options)
+		if err == nil && cache {
+			saveListCache(res)
+		}
+	}
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	items := filter(api.ItemList(res.Items()))
+
+	if asJSONL {
+		encoder := json.NewEncoder(stdout)
+		for _, item := range items {
+			if err := encoder.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if asJSON, ok := arguments["--json"].(bool); ok && asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(items)
+	}
+
+	if asCSV {
+		return writeCSV(stdout, items)
 	}
 
 	var itemTemplate *template.Template
 	if format, ok := arguments["--format"].(string); ok {
-		itemTemplate = template.Must(template.New("item").Parse(format))
+		itemTemplate, err = parseItemTemplate(format)
+		if err != nil {
+			return err
+		}
+	} else if useColor(stdout) {
+		itemTemplate = colorItemTemplate
 	} else {
 		itemTemplate = defaultItemTemplate
 	}
 
-	items := []api.Item{}
-	for _, item := range res.List {
-		items = append(items, item)
+	for _, item := range items {
+		err := itemTemplate.Execute(stdout, item)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, "")
 	}
 
-	sort.Sort(bySortID(items))
+	return nil
+}
+
+// writeCSV writes items as CSV with a header row.
+func writeCSV(w io.Writer, items []api.Item) error {
+	out := csv.NewWriter(w)
+
+	err := out.Write([]string{"item_id", "title", "url", "tags", "time_added"})
+	if err != nil {
+		return err
+	}
 
 	for _, item := range items {
-		err := itemTemplate.Execute(os.Stdout, item)
+		timeAdded := ""
+		if t := item.TimeAdded.Time(); !t.IsZero() {
+			timeAdded = t.UTC().Format(time.RFC3339)
+		}
+
+		err := out.Write([]string{
+			strconv.FormatInt(item.ItemID, 10),
+			item.Title(),
+			item.URL(),
+			strings.Join(item.TagNames(), ","),
+			timeAdded,
+		})
 		if err != nil {
-			panic(err)
+			return err
 		}
-		fmt.Println("")
 	}
+
+	out.Flush()
+	return out.Error()
 }
 
-func commandArchive(arguments map[string]interface{}, client *api.Client) {
-	if itemIDString, ok := arguments["<item-id>"].(string); ok {
-		itemID, err := strconv.Atoi(itemIDString)
+// listCacheFile is the file in the config directory keeping the result of the
+// last list, for list --offline.
+const listCacheFile = "list-cache.json"
+
+// saveListCache replaces the cached list with res. Failing to is only logged,
+// as the list was retrieved anyway.
+func saveListCache(res *api.RetrieveResult) {
+	err := ioutil.WriteFile(filepath.Join(configDir, listCacheFile), res.RawJSON(), 0600)
+	if err != nil {
+		logger.Printf("Can't cache the list: %v", err)
+	}
+}
+
+// loadListCache returns the cached list, reporting how old it is on stderr.
+func loadListCache() (*api.RetrieveResult, error) {
+	path := filepath.Join(configDir, listCacheFile)
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, errors.New("no list is cached yet, run pocket list without --offline first")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res := &api.RetrieveResult{}
+	if err := loadJSONFromFile(path, res); err != nil {
+		return nil, err
+	}
+
+	age := time.Since(info.ModTime()).Round(time.Second)
+	fmt.Fprintf(stderr, "Showing the list cached %v ago\n", age)
+	return res, nil
+}
+
+func commandCount(arguments map[string]interface{}, client *api.Client) error {
+	options, err := listOptions(arguments)
+	if err != nil {
+		return err
+	}
+
+	items, err := client.RetrieveAll(options)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, len(items))
+	return nil
+}
+
+func commandGet(arguments map[string]interface{}, client *api.Client) error {
+	itemID, err := itemIDArgument(arguments)
+	if err != nil {
+		return err
+	}
+
+	item, err := getItem(client, itemID)
+	if err != nil {
+		return err
+	}
+
+	if asJSON, ok := arguments["--json"].(bool); ok && asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(item)
+	}
+
+	itemTemplate := detailedItemTemplate
+	if format, ok := arguments["--format"].(string); ok {
+		itemTemplate, err = parseItemTemplate(format)
 		if err != nil {
-			panic(err)
+			return err
 		}
+	}
 
-		action := api.NewArchiveAction(itemID)
-		res, err := client.Modify(action)
-		fmt.Println(res, err)
-	} else {
-		panic("Wrong arguments")
+	err = itemTemplate.Execute(stdout, item)
+	if err != nil {
+		return err
 	}
+	fmt.Fprintln(stdout, "")
+	return nil
 }
 
-func commandAdd(arguments map[string]interface{}, client *api.Client) {
-	options := &api.AddOption{}
+var errItemFound = errors.New("item found")
 
-	url, ok := arguments["<url>"].(string)
-	if !ok {
-		panic("Wrong arguments")
+// getItem looks up a single item. The retrieve API has no way to ask for an
+// item by its id, so every item is listed until it is found.
+func getItem(client *api.Client, itemID int) (*api.Item, error) {
+	options := &api.RetrieveOption{
+		State:      api.StateAll,
+		DetailType: api.DetailTypeComplete,
 	}
 
-	options.URL = url
+	var found api.Item
+	err := client.RetrieveEach(options, func(item api.Item) error {
+		if item.ItemID != int64(itemID) {
+			return nil
+		}
+		found = item
+		return errItemFound
+	})
+	if err == errItemFound {
+		return &found, nil
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	if title, ok := arguments["--title"].(string); ok {
-		options.Title = title
+	return nil, fmt.Errorf("item %d not found", itemID)
+}
+
+func commandArchive(arguments map[string]interface{}, client *api.Client) error {
+	itemIDs, err := itemIDsArgument(arguments)
+	if err != nil {
+		return err
 	}
 
-	if tags, ok := arguments["--tags"].(string); ok {
-		options.Tags = tags
+	actions := []*api.Action{}
+	for _, itemID := range itemIDs {
+		actions = append(actions, api.NewArchiveAction(itemID))
 	}
 
-	err := client.Add(options)
+	return performActions(client, actions, "Archived")
+}
+
+func commandUnarchive(arguments map[string]interface{}, client *api.Client) error {
+	itemID, err := itemIDArgument(arguments)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		return err
 	}
+
+	return performAction(client, api.NewReaddAction(itemID), "Unarchived")
 }
 
-func commandSpotlight(arguments map[string]interface{}, client *api.Client) {
-	badChars, err := regexp.Compile(`[^a-zA-Z0-9'". _-|()[]`)
+func commandFavorite(arguments map[string]interface{}, client *api.Client) error {
+	itemID, err := itemIDArgument(arguments)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		return err
 	}
-	repeatSpace, err := regexp.Compile(`\s+`)
+
+	if unfavorite, ok := arguments["unfavorite"].(bool); ok && unfavorite {
+		return performAction(client, api.NewUnfavoriteAction(itemID), "Unfavorited")
+	}
+
+	return performAction(client, api.NewFavoriteAction(itemID), "Favorited")
+}
+
+func commandDelete(arguments map[string]interface{}, client *api.Client) error {
+	itemIDs, err := itemIDsArgument(arguments)
+	if err != nil {
+		return err
+	}
+
+	confirmed, err := confirmDelete(arguments, itemIDs)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		return err
+	}
+	if !confirmed {
+		fmt.Fprintln(stdout, "Aborted")
+		return nil
+	}
+
+	actions := []*api.Action{}
+	for _, itemID := range itemIDs {
+		actions = append(actions, api.NewDeleteAction(itemID))
+	}
+
+	return performActions(client, actions, "Deleted")
+}
+
+// confirmDelete asks whether to permanently delete the items, unless --force
+// or --dry-run is given.
+func confirmDelete(arguments map[string]interface{}, itemIDs []int) (bool, error) {
+	if force, ok := arguments["--force"].(bool); (ok && force) || dryRun {
+		return true, nil
+	}
+
+	if readsStdin(arguments) {
+		return false, fmt.Errorf("--force is required when reading item ids from stdin")
+	}
+
+	if len(itemIDs) == 1 {
+		fmt.Fprintf(stdout, "Permanently delete item %d? [y/N] ", itemIDs[0])
+	} else {
+		fmt.Fprintf(stdout, "Permanently delete %d items? [y/N] ", len(itemIDs))
+	}
+	answer, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
 	}
-	leadingNoise, err := regexp.Compile(`^[ \t_-]+`)
+
+	return strings.TrimSpace(answer) == "y", nil
+}
+
+func commandTag(arguments map[string]interface{}, client *api.Client) error {
+	itemID, err := itemIDArgument(arguments)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		return err
 	}
-	trailingNoise, err := regexp.Compile(`[ \t_-]+$`)
+
+	tags := splitTags(arguments["<tags>"].(string))
+
+	if remove, ok := arguments["remove"].(bool); ok && remove {
+		action, err := api.NewTagsRemoveAction(itemID, tags)
+		if err != nil {
+			return err
+		}
+		return performAction(client, action, "Removed tags from")
+	}
+
+	action, err := api.NewTagsAddAction(itemID, tags)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		return err
 	}
+	return performAction(client, action, "Added tags to")
+}
+
+func commandTags(arguments map[string]interface{}, client *api.Client) error {
+	byName := false
+	if order, ok := arguments["--sort"].(string); ok {
+		switch order {
+		case "count":
+		case "name":
+			byName = true
+		default:
+			return fmt.Errorf("unknown sort %q, expected count or name", order)
+		}
+	}
+
 	options := &api.RetrieveOption{
 		State: api.StateAll,
+		// Tags are only included with complete details.
+		DetailType: api.DetailTypeComplete,
 	}
 
-	res, err := client.Retrieve(options)
+	items, err := client.RetrieveAll(options)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		return err
 	}
 
-	itemTemplate := spotlightItemTemplate
+	counts := map[string]int{}
+	for _, item := range items {
+		for _, tag := range item.TagNames() {
+			counts[tag]++
+		}
+	}
 
-	var indexDir string
-	if dir, ok := arguments["--indexdir"].(string); ok {
-		indexDir = dir
-	} else {
-		// NOTE: This must not be a hidden path or spotlight won't index it
-		home := os.Getenv("HOME")
-		if home == "" {
-			fmt.Fprintln(os.Stderr, "$HOME not set")
-			os.Exit(1)
+	tags := make([]string, 0, len(counts))
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if !byName && counts[tags[i]] != counts[tags[j]] {
+			return counts[tags[i]] > counts[tags[j]]
 		}
-		indexDir = filepath.Join(home, "Library/Caches/Metadata/go-pocket")
+		return tags[i] < tags[j]
+	})
+
+	for _, tag := range tags {
+		fmt.Fprintf(stdout, "%d\t%s\n", counts[tag], tag)
 	}
-	err = os.RemoveAll(indexDir)
+	return nil
+}
+
+func commandRenameTag(arguments map[string]interface{}, client *api.Client) error {
+	oldTag := arguments["<old>"].(string)
+	newTag := arguments["<new>"].(string)
+
+	action := api.NewTagRenameAction(oldTag, newTag)
+	if dryRun {
+		return printActions([]*api.Action{action})
+	}
+
+	res, err := client.Modify(action)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		return err
+	}
+
+	if len(res.ActionResults) == 0 || !res.ActionResults[0] {
+		return fmt.Errorf("renaming tag %q failed", oldTag)
+	}
+
+	// Pocket doesn't report how many items carried the tag.
+	fmt.Fprintf(stdout, "Renamed tag %q to %q\n", oldTag, newTag)
+	return nil
+}
+
+// splitTags splits a comma-separated list of tags, dropping surrounding
+// whitespace and empty entries.
+func splitTags(s string) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
 	}
-	err = os.MkdirAll(indexDir, 0700)
+	return tags
+}
+
+// itemIDArgument parses the <item-id> argument.
+func itemIDArgument(arguments map[string]interface{}) (int, error) {
+	itemIDString, ok := arguments["<item-id>"].(string)
+	if !ok {
+		return 0, fmt.Errorf("Wrong arguments")
+	}
+
+	itemID, err := strconv.Atoi(itemIDString)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		return 0, fmt.Errorf("invalid item id %q", itemIDString)
+	}
+
+	return itemID, nil
+}
+
+// itemIDsArgument parses the <item-ids> arguments. An argument of "-" reads
+// newline-separated item ids from stdin in its place. It is an error for no
+// ids to be given, as when stdin is empty.
+func itemIDsArgument(arguments map[string]interface{}) ([]int, error) {
+	itemIDStrings, ok := arguments["<item-ids>"].([]string)
+	if !ok {
+		return nil, fmt.Errorf("Wrong arguments")
 	}
 
-	for _, item := range res.List {
-		h := sha256.New()
-		_, err = h.Write([]byte(item.URL()))
+	itemIDs := []int{}
+	for _, itemIDString := range itemIDStrings {
+		if itemIDString == "-" {
+			read, err := readItemIDs(stdin)
+			if err != nil {
+				return nil, err
+			}
+			itemIDs = append(itemIDs, read...)
+			continue
+		}
+
+		itemID, err := strconv.Atoi(itemIDString)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error calculating hash: %v\n", err)
-			os.Exit(1)
+			return nil, fmt.Errorf("invalid item id %q", itemIDString)
 		}
-		fname := item.Title()
-		fname = badChars.ReplaceAllString(fname, "")
-		fname = repeatSpace.ReplaceAllString(fname, " ")
-		fname = leadingNoise.ReplaceAllString(fname, "")
-		fname = trailingNoise.ReplaceAllString(fname, "")
-		fnameRunes := []rune(fname)
-		if len(fnameRunes) > maxTitle {
-			fname = string(fnameRunes[0:maxTitle])
+		itemIDs = append(itemIDs, itemID)
+	}
+
+	if len(itemIDs) == 0 {
+		return nil, errors.New("no item ids given")
+	}
+
+	return itemIDs, nil
+}
+
+// readsStdin reports whether the <item-ids> arguments are read from stdin.
+func readsStdin(arguments map[string]interface{}) bool {
+	itemIDStrings, _ := arguments["<item-ids>"].([]string)
+	for _, itemIDString := range itemIDStrings {
+		if itemIDString == "-" {
+			return true
 		}
-		fpath := filepath.Join(indexDir, fmt.Sprintf("%s.webloc", fname))
+	}
+	return false
+}
 
-		fout, err := os.Create(fpath)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+// readItemIDs reads one item id per line from r, skipping blank lines.
+func readItemIDs(r io.Reader) ([]int, error) {
+	itemIDs := []int{}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
 		}
-		err = itemTemplate.Execute(fout, item)
+
+		itemID, err := strconv.Atoi(text)
 		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			fout.Close()
-			os.Exit(1)
+			return nil, fmt.Errorf("line %d: invalid item id %q", line, text)
 		}
-		err = fout.Close()
-		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+		itemIDs = append(itemIDs, itemID)
+	}
+
+	return itemIDs, scanner.Err()
+}
+
+// performAction sends a single action and reports whether Pocket applied it,
+// printing done and the item id on success.
+func performAction(client *api.Client, action *api.Action, done string) error {
+	return performActions(client, []*api.Action{action}, done)
+}
+
+// performActions sends the item actions in a single request, printing done
+// and the item id for each one Pocket applied. The ids of those which failed
+// are reported in the returned error. With --dry-run the actions are printed
+// and nothing is sent.
+func performActions(client *api.Client, actions []*api.Action, done string) error {
+	if dryRun {
+		return printActions(actions)
+	}
+
+	res, err := client.ModifyBatch(actions...)
+	if err != nil {
+		return err
+	}
+
+	failed := []string{}
+	for i, action := range actions {
+		if i >= len(res.ActionResults) || !res.ActionResults[i] {
+			failed = append(failed, strconv.Itoa(action.ItemID))
+			continue
 		}
-		plutilOut, err := exec.Command("/usr/bin/plutil", "-convert", "binary1", fpath).CombinedOutput()
+		fmt.Fprintf(stdout, "%s %d\n", done, action.ItemID)
+	}
+
+	switch len(failed) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("%s failed for item %s", actions[0].Action, failed[0])
+	default:
+		return fmt.Errorf("%s failed for items %s", actions[0].Action, strings.Join(failed, ", "))
+	}
+}
+
+// printActions prints the actions --dry-run keeps from being sent.
+func printActions(actions []*api.Action) error {
+	for _, action := range actions {
+		b, err := json.Marshal(action)
 		if err != nil {
-			fmt.Fprintln(os.Stderr, plutilOut)
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+			return err
 		}
+		fmt.Fprintf(stdout, "Would send %s\n", b)
+	}
+	return nil
+}
+
+// printAddActions prints the add actions AddBatch would send for options,
+// leaving out those with invalid tags.
+func printAddActions(options []*api.AddOption) error {
+	actions := []*api.Action{}
+	for _, option := range options {
+		if action, err := api.NewAddAction(option); err == nil {
+			actions = append(actions, action)
+		}
+	}
+	return printActions(actions)
+}
+
+func commandAdd(arguments map[string]interface{}, client *api.Client) error {
+	options := &api.AddOption{}
+
+	url, ok := arguments["<url>"].(string)
+	if !ok {
+		return errors.New("Wrong arguments")
+	}
+
+	options.URL = url
+
+	if title, ok := arguments["--title"].(string); ok {
+		options.Title = title
+	}
+
+	if tags, ok := arguments["--tags"].(string); ok {
+		options.TagList = splitTags(tags)
 	}
-	mdimportOut, err := exec.Command("/usr/bin/mdimport", indexDir).CombinedOutput()
+
+	res, err := client.Add(options)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, mdimportOut)
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		return err
 	}
+
+	fmt.Fprintln(stdout, res.ItemID)
+	return nil
 }
 
-func getConsumerKey() string {
-	consumerKeyPath := filepath.Join(configDir, "consumer_key")
+// die prints err and exits with a non-zero status.
+func die(err error) {
+	fmt.Fprintln(stderr, err)
+	exit(1)
+}
+
+// getConsumerKey returns the consumer key from the --consumer-key option, the
+// POCKET_CONSUMER_KEY environment variable, the consumer_key file in the
+// config directory, or by prompting for it, in that order of precedence. A key
+// given with --consumer-key is not saved.
+func getConsumerKey(arguments map[string]interface{}) (string, error) {
+	if consumerKey, ok := arguments["--consumer-key"].(string); ok && consumerKey != "" {
+		return consumerKey, nil
+	}
+
+	return loadConsumerKey(os.Getenv("POCKET_CONSUMER_KEY"), filepath.Join(configDir, "consumer_key"))
+}
+
+func loadConsumerKey(envConsumerKey, consumerKeyPath string) (string, error) {
+	if envConsumerKey != "" {
+		return envConsumerKey, nil
+	}
+
 	consumerKey, err := ioutil.ReadFile(consumerKeyPath)
 
 	if err != nil {
-		log.Printf("Can't get consumer key: %v", err)
-		log.Print("Enter your consumer key (from here https://getpocket.com/developer/apps/): ")
+		logger.Printf("Can't get consumer key: %v", err)
+		fmt.Fprint(stderr, "Enter your consumer key (from here https://getpocket.com/developer/apps/): ")
 
-		consumerKey, _, err = bufio.NewReader(os.Stdin).ReadLine()
+		consumerKey, _, err = bufio.NewReader(stdin).ReadLine()
 		if err != nil {
-			panic(err)
+			return "", err
 		}
 
 		err = ioutil.WriteFile(consumerKeyPath, consumerKey, 0600)
 		if err != nil {
-			panic(err)
+			return "", err
 		}
 
-		return string(consumerKey)
+		return string(consumerKey), nil
 	}
 
-	return string(bytes.SplitN(consumerKey, []byte("\n"), 2)[0])
+	return string(bytes.SplitN(consumerKey, []byte("\n"), 2)[0]), nil
 }
 
-func restoreAccessToken(consumerKey string) (*auth.Authorization, error) {
+// restoreAccessToken returns the access token from the POCKET_ACCESS_TOKEN
+// environment variable or the auth.json file in the config directory. When
+// neither is available it runs the OAuth flow and saves the result to
+// auth.json.
+func restoreAccessToken(consumerKey string, options *authOptions) (*auth.Authorization, error) {
+	return loadAccessToken(
+		os.Getenv("POCKET_ACCESS_TOKEN"),
+		filepath.Join(configDir, "auth.json"),
+		func() (*auth.Authorization, error) { return obtainAccessToken(consumerKey, options) },
+	)
+}
+
+func loadAccessToken(envAccessToken, authFile string, obtain func() (*auth.Authorization, error)) (*auth.Authorization, error) {
+	if envAccessToken != "" {
+		return &auth.Authorization{AccessToken: envAccessToken}, nil
+	}
+
 	accessToken := &auth.Authorization{}
-	authFile := filepath.Join(configDir, "auth.json")
 
 	err := loadJSONFromFile(authFile, accessToken)
 
 	if err != nil {
-		log.Println(err)
+		logger.Println(err)
 
-		accessToken, err = obtainAccessToken(consumerKey)
+		accessToken, err = obtain()
 		if err != nil {
 			return nil, err
 		}
@@ -375,34 +1293,20 @@ func restoreAccessToken(consumerKey string) (*auth.Authorization, error) {
 	return accessToken, nil
 }
 
-func obtainAccessToken(consumerKey string) (*auth.Authorization, error) {
-	ch := make(chan struct{})
-	ts := httptest.NewServer(
-		http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-			if req.URL.Path == "/favicon.ico" {
-				http.Error(w, "Not Found", 404)
-				return
-			}
-
-			w.Header().Set("Content-Type", "text/plain")
-			fmt.Fprintln(w, "Authorized.")
-			ch <- struct{}{}
-		}))
-	defer ts.Close()
-
-	redirectURL := ts.URL
-
-	requestToken, err := auth.ObtainRequestToken(consumerKey, redirectURL)
+// reauthorize obtains a new access token in place of one Pocket rejected, and
+// saves it.
+func reauthorize(consumerKey string, options *authOptions) (*auth.Authorization, error) {
+	accessToken, err := obtainAccessToken(consumerKey, options)
 	if err != nil {
 		return nil, err
 	}
 
-	url := auth.GenerateAuthorizationURL(requestToken, redirectURL)
-	fmt.Println(url)
-
-	<-ch
+	err = saveJSONToFile(filepath.Join(configDir, "auth.json"), accessToken)
+	if err != nil {
+		return nil, err
+	}
 
-	return auth.ObtainAccessToken(consumerKey, requestToken)
+	return accessToken, nil
 }
 
 func saveJSONToFile(path string, v interface{}) error {
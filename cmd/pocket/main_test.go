@@ -0,0 +1,1443 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bvp/go-pocket/api"
+	"github.com/bvp/go-pocket/api/apitest"
+	"github.com/bvp/go-pocket/auth"
+	"github.com/docopt/docopt-go"
+	. "github.com/onsi/gomega"
+)
+
+const testList = `{
+	"list":{
+		"2":{"item_id":"2","resolved_title":"Second, with a comma","resolved_url":"https://example.com/2","sort_id":1,"time_added":"1577836900"},
+		"1":{"item_id":"1","given_title":"First","given_url":"https://example.com/1","sort_id":0,"time_added":"1577836800"}
+	},
+	"status":1
+}`
+
+// TestMain points configDir at a temporary directory, so that commands which
+// save files there, like list, leave the real one alone.
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "pocket-config")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	configDir = dir
+
+	status := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(status)
+}
+
+// newTestClient returns a client talking to a fake Pocket server which serves
+// handler. The server must be closed by the caller.
+func newTestClient(handler http.HandlerFunc) (*api.Client, *httptest.Server) {
+	ts := httptest.NewServer(handler)
+
+	client := api.NewClient("consumer", "token")
+	client.BaseURL = ts.URL + "/v3"
+	return client, ts
+}
+
+// addAction creates an add action for seeding test servers.
+func addAction(options *api.AddOption) *api.Action {
+	action, err := api.NewAddAction(options)
+	Expect(err).To(BeNil())
+	return action
+}
+
+// captureStdout redirects command output into the returned buffer until
+// resetStdout is called.
+func captureStdout() *bytes.Buffer {
+	out := &bytes.Buffer{}
+	stdout = out
+	return out
+}
+
+func resetStdout() {
+	stdout = os.Stdout
+}
+
+func TestCommandListJSON(t *testing.T) {
+	RegisterTestingT(t)
+
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testList))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"list", "--json"})
+	Expect(err).To(BeNil())
+
+	Expect(commandList(arguments, client)).To(Succeed())
+
+	var items []api.Item
+	Expect(json.Unmarshal(out.Bytes(), &items)).To(Succeed())
+	Expect(items).To(HaveLen(2))
+	Expect(items[0].ItemID).To(Equal(int64(1)))
+	Expect(items[0].GivenURL).To(Equal("https://example.com/1"))
+	Expect(items[1].ResolvedTitle).To(Equal("Second, with a comma"))
+	Expect(items[1].ResolvedURL).To(Equal("https://example.com/2"))
+	Expect(items[1].TimeAdded.Time()).To(Equal(time.Unix(1577836900, 0)))
+}
+
+func TestCommandListJSONL(t *testing.T) {
+	RegisterTestingT(t)
+
+	server := apitest.NewServer()
+	defer server.Close()
+	_, err := server.Client().ModifyBatch(
+		addAction(&api.AddOption{URL: "https://example.com/1", Title: "First", Time: 1577836800}),
+		addAction(&api.AddOption{URL: "https://example.com/2", Title: "Second\nline", Time: 1577836900}),
+	)
+	Expect(err).To(BeNil())
+	out := captureStdout()
+	defer resetStdout()
+
+	for _, argv := range [][]string{
+		{"list", "--jsonl"},
+		{"list", "--jsonl", "--count", "2"},
+	} {
+		out.Reset()
+		arguments, err := parseArguments(argv)
+		Expect(err).To(BeNil())
+		Expect(commandList(arguments, server.Client())).To(Succeed())
+
+		lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+		Expect(lines).To(HaveLen(2), strings.Join(argv, " "))
+
+		titles := []string{}
+		for _, line := range lines {
+			var item api.Item
+			Expect(json.Unmarshal([]byte(line), &item)).To(Succeed())
+			titles = append(titles, item.Title())
+		}
+		Expect(titles).To(Equal([]string{"Second\nline", "First"}))
+	}
+}
+
+func TestCommandListOffline(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	defer func(dir string) { configDir = dir }(configDir)
+	defer func() { stderr = os.Stderr }()
+
+	configDir = dir
+	errOut := &bytes.Buffer{}
+	stderr = errOut
+
+	requests := 0
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var options map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&options)
+		if _, ok := options["tag"]; ok {
+			w.Write([]byte(`{"list":{"3":{"item_id":"3","given_title":"Third"}},"status":1}`))
+			return
+		}
+		w.Write([]byte(testList))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"list", "--offline"})
+	Expect(err).To(BeNil())
+	Expect(commandList(arguments, client)).To(MatchError(ContainSubstring("no list is cached yet")))
+
+	online, err := parseArguments([]string{"list"})
+	Expect(err).To(BeNil())
+	Expect(commandList(online, client)).To(Succeed())
+	listed := out.String()
+
+	out.Reset()
+	Expect(commandList(arguments, client)).To(Succeed())
+	Expect(out.String()).To(Equal(listed))
+	Expect(errOut.String()).To(MatchRegexp(`^Showing the list cached \d+s ago\n$`))
+	Expect(requests).To(Equal(1))
+
+	// Filtered lists leave the cache alone.
+	filtered, err := parseArguments([]string{"list", "--tag", "a", "--count", "1"})
+	Expect(err).To(BeNil())
+	Expect(commandList(filtered, client)).To(Succeed())
+	Expect(out.String()).To(ContainSubstring("Third"))
+	Expect(requests).To(Equal(2))
+
+	out.Reset()
+	Expect(commandList(arguments, client)).To(Succeed())
+	Expect(out.String()).To(Equal(listed))
+
+	filtered, err = parseArguments([]string{"list", "--offline", "--tag", "b", "--favorite"})
+	Expect(err).To(BeNil())
+	Expect(commandList(filtered, client)).To(MatchError("--offline cannot be used with --tag, --favorite"))
+	Expect(requests).To(Equal(2))
+}
+
+func TestCommandListOfflineTags(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	defer func(dir string) { configDir = dir }(configDir)
+	defer func() { stderr = os.Stderr }()
+
+	configDir = dir
+	stderr = ioutil.Discard
+
+	// Like Pocket, tags are only sent with complete details.
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		var options map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&options)
+		tags := ""
+		if options["detailType"] == "complete" {
+			tags = `,"tags":{"go":{"item_id":"1","tag":"go"}}`
+		}
+		w.Write([]byte(`{"list":{
+			"1":{"item_id":"1","given_title":"First","sort_id":0` + tags + `},
+			"2":{"item_id":"2","given_title":"Second","sort_id":1}
+		},"status":1}`))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"list"})
+	Expect(err).To(BeNil())
+	Expect(commandList(arguments, client)).To(Succeed())
+
+	for _, test := range []struct {
+		argv   []string
+		listed string
+	}{
+		{[]string{"list", "--offline", "--untagged", "--format", "{{.ItemID}}"}, "2\n"},
+		{[]string{"list", "--offline", "--tags-all", "go", "--format", "{{.ItemID}}"}, "1\n"},
+	} {
+		out.Reset()
+		arguments, err = parseArguments(test.argv)
+		Expect(err).To(BeNil())
+		Expect(commandList(arguments, client)).To(Succeed())
+		Expect(out.String()).To(Equal(test.listed))
+	}
+}
+
+func TestCommandListFormat(t *testing.T) {
+	RegisterTestingT(t)
+
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testList))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"list", "--format", "{{.ItemID}} {{.Title}}"})
+	Expect(err).To(BeNil())
+
+	Expect(commandList(arguments, client)).To(Succeed())
+
+	Expect(out.String()).To(Equal("1 First\n2 Second, with a comma\n"))
+}
+
+func TestListJSONExcludesFormat(t *testing.T) {
+	RegisterTestingT(t)
+
+	_, err := docopt.Parse(fmt.Sprintf(usage, getFields()), []string{"list", "--json", "--format", "x"}, true, version, false, false)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestCommandListCSV(t *testing.T) {
+	RegisterTestingT(t)
+
+	var data map[string]interface{}
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&data)
+		w.Write([]byte(`{
+			"list":{
+				"1":{"item_id":"1","resolved_title":"Commas, \"quotes\"\nand newlines","resolved_url":"https://example.com/1",
+					"sort_id":0,"time_added":"1577836800","tags":{"go":{"tag":"go"},"read later":{"tag":"read later"}}},
+				"2":{"item_id":"2","given_title":"Plain","given_url":"https://example.com/2","sort_id":1,"time_added":"0","tags":[]}
+			},
+			"status":1
+		}`))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"list", "--csv"})
+	Expect(err).To(BeNil())
+
+	Expect(commandList(arguments, client)).To(Succeed())
+
+	Expect(data).To(HaveKeyWithValue("detailType", "complete"))
+
+	Expect(out.String()).To(ContainSubstring("1,\"Commas, \"\"quotes\"\"\nand newlines\",https://example.com/1,\"go,read later\","))
+
+	records, err := csv.NewReader(out).ReadAll()
+	Expect(err).To(BeNil())
+	Expect(records).To(Equal([][]string{
+		{"item_id", "title", "url", "tags", "time_added"},
+		{"1", "Commas, \"quotes\"\nand newlines", "https://example.com/1", "go,read later", "2020-01-01T00:00:00Z"},
+		{"2", "Plain", "https://example.com/2", "", ""},
+	}))
+}
+
+func TestListOptionsPaging(t *testing.T) {
+	RegisterTestingT(t)
+
+	arguments, err := parseArguments([]string{"list", "--count", "20", "--offset", "40", "--tag", "go"})
+	Expect(err).To(BeNil())
+
+	options, err := listOptions(arguments)
+	Expect(err).To(BeNil())
+	Expect(options).To(Equal(&api.RetrieveOption{Tag: "go", Count: 20, Offset: 40}))
+
+	for _, argv := range [][]string{
+		{"list", "--count", "-1"},
+		{"list", "--offset", "ten"},
+	} {
+		arguments, err := parseArguments(argv)
+		Expect(err).To(BeNil())
+
+		_, err = listOptions(arguments)
+		Expect(err).NotTo(BeNil())
+	}
+}
+
+func TestParseState(t *testing.T) {
+	RegisterTestingT(t)
+
+	for value, state := range map[string]api.State{
+		"unread":  api.StateUnread,
+		"archive": api.StateArchive,
+		"all":     api.StateAll,
+	} {
+		parsed, err := parseState(value)
+		Expect(err).To(BeNil())
+		Expect(parsed).To(Equal(state))
+	}
+
+	_, err := parseState("starred")
+	Expect(err).To(MatchError(ContainSubstring("unread, archive and all")))
+
+	arguments, err := parseArguments([]string{"list", "--state", "archive"})
+	Expect(err).To(BeNil())
+
+	options, err := listOptions(arguments)
+	Expect(err).To(BeNil())
+	Expect(options.State).To(Equal(api.State(api.StateArchive)))
+}
+
+func TestListOptionsFavoriteAndSort(t *testing.T) {
+	RegisterTestingT(t)
+
+	arguments, err := parseArguments([]string{"list", "--favorite", "--sort", "oldest", "--domain", "example.com"})
+	Expect(err).To(BeNil())
+
+	options, err := listOptions(arguments)
+	Expect(err).To(BeNil())
+	Expect(options.Favorite).To(Equal(api.FavoriteFilter(api.FavoriteFilterFavorited)))
+	Expect(options.Sort).To(Equal(api.Sort(api.SortOldest)))
+	Expect(options.Domain).To(Equal("example.com"))
+
+	arguments, err = parseArguments([]string{"list"})
+	Expect(err).To(BeNil())
+
+	options, err = listOptions(arguments)
+	Expect(err).To(BeNil())
+	Expect(options.Favorite).To(Equal(api.FavoriteFilterUnspecified))
+	Expect(options.Sort).To(BeEmpty())
+}
+
+func TestParseSort(t *testing.T) {
+	RegisterTestingT(t)
+
+	for _, order := range []string{"newest", "oldest", "title", "site"} {
+		parsed, err := parseSort(order)
+		Expect(err).To(BeNil())
+		Expect(string(parsed)).To(Equal(order))
+	}
+
+	_, err := parseSort("random")
+	Expect(err).To(MatchError(ContainSubstring("newest, oldest, title and site")))
+
+	arguments, err := parseArguments([]string{"list", "--sort", "random"})
+	Expect(err).To(BeNil())
+
+	_, err = listOptions(arguments)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestCommandFavorite(t *testing.T) {
+	RegisterTestingT(t)
+
+	var body string
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Write([]byte(`{"action_results":[true],"status":1}`))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"favorite", "42"})
+	Expect(err).To(BeNil())
+
+	Expect(commandFavorite(arguments, client)).To(Succeed())
+	Expect(body).To(ContainSubstring(`{"action":"favorite","item_id":"42"}`))
+	Expect(out.String()).To(Equal("Favorited 42\n"))
+
+	arguments, err = parseArguments([]string{"unfavorite", "42"})
+	Expect(err).To(BeNil())
+
+	Expect(commandFavorite(arguments, client)).To(Succeed())
+	Expect(body).To(ContainSubstring(`{"action":"unfavorite","item_id":"42"}`))
+}
+
+func TestCommandFavoriteFailed(t *testing.T) {
+	RegisterTestingT(t)
+
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"action_results":[false],"status":1}`))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"favorite", "42"})
+	Expect(err).To(BeNil())
+
+	Expect(commandFavorite(arguments, client)).To(MatchError("favorite failed for item 42"))
+	Expect(out.String()).To(BeEmpty())
+
+	arguments, err = parseArguments([]string{"favorite", "abc"})
+	Expect(err).To(BeNil())
+
+	Expect(commandFavorite(arguments, client)).To(MatchError(`invalid item id "abc"`))
+}
+
+func TestCommandDeleteForce(t *testing.T) {
+	RegisterTestingT(t)
+
+	var body string
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Write([]byte(`{"action_results":[true],"status":1}`))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"delete", "42", "--force"})
+	Expect(err).To(BeNil())
+
+	Expect(commandDelete(arguments, client)).To(Succeed())
+	Expect(body).To(ContainSubstring(`{"action":"delete","item_id":"42"}`))
+	Expect(out.String()).To(Equal("Deleted 42\n"))
+}
+
+func TestDryRun(t *testing.T) {
+	RegisterTestingT(t)
+
+	requests := 0
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"action_results":[true],"status":1}`))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+	defer func() { dryRun = false }()
+
+	dryRun = true
+
+	arguments, err := parseArguments([]string{"archive", "1", "2", "--dry-run"})
+	Expect(err).To(BeNil())
+	Expect(commandArchive(arguments, client)).To(Succeed())
+
+	// No confirmation is asked for, as nothing is deleted.
+	arguments, err = parseArguments([]string{"delete", "3", "--dry-run"})
+	Expect(err).To(BeNil())
+	Expect(commandDelete(arguments, client)).To(Succeed())
+
+	arguments, err = parseArguments([]string{"tag", "add", "4", "go", "--dry-run"})
+	Expect(err).To(BeNil())
+	Expect(commandTag(arguments, client)).To(Succeed())
+
+	arguments, err = parseArguments([]string{"rename-tag", "golang", "go", "--dry-run"})
+	Expect(err).To(BeNil())
+	Expect(commandRenameTag(arguments, client)).To(Succeed())
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	bookmarks := filepath.Join(dir, "bookmarks.html")
+	Expect(ioutil.WriteFile(bookmarks, []byte(`<DL><DT><A HREF="https://example.com/5">Fifth</A></DL>`), 0600)).To(Succeed())
+	arguments, err = parseArguments([]string{"import", bookmarks, "--dry-run"})
+	Expect(err).To(BeNil())
+	Expect(commandImport(arguments, client)).To(Succeed())
+
+	backup := filepath.Join(dir, "backup.json")
+	Expect(ioutil.WriteFile(backup, []byte(`[{"item_id":"6","given_url":"https://example.com/6","status":"1"}]`), 0600)).To(Succeed())
+	arguments, err = parseArguments([]string{"restore", backup, "--dry-run"})
+	Expect(err).To(BeNil())
+	Expect(commandRestore(arguments, client)).To(Succeed())
+
+	Expect(requests).To(Equal(0))
+	Expect(out.String()).To(Equal(`Would send {"action":"archive","item_id":"1"}
+Would send {"action":"archive","item_id":"2"}
+Would send {"action":"delete","item_id":"3"}
+Would send {"action":"tags_add","item_id":"4","tags":"go"}
+Would send {"action":"tag_rename","old_tag":"golang","new_tag":"go"}
+Would send {"action":"add","url":"https://example.com/5","title":"Fifth"}
+Would send {"action":"add","url":"https://example.com/6"}
+`))
+}
+
+func TestCommandDeleteConfirmation(t *testing.T) {
+	RegisterTestingT(t)
+
+	requests := 0
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"action_results":[true],"status":1}`))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+	defer func() { stdin = os.Stdin }()
+
+	arguments, err := parseArguments([]string{"delete", "42"})
+	Expect(err).To(BeNil())
+
+	for _, answer := range []string{"n\n", "yes\n", "\n", ""} {
+		out.Reset()
+		stdin = strings.NewReader(answer)
+
+		Expect(commandDelete(arguments, client)).To(Succeed())
+		Expect(out.String()).To(Equal("Permanently delete item 42? [y/N] Aborted\n"))
+	}
+	Expect(requests).To(Equal(0))
+
+	out.Reset()
+	stdin = strings.NewReader("y\n")
+
+	Expect(commandDelete(arguments, client)).To(Succeed())
+	Expect(out.String()).To(Equal("Permanently delete item 42? [y/N] Deleted 42\n"))
+	Expect(requests).To(Equal(1))
+}
+
+func TestCommandUnarchive(t *testing.T) {
+	RegisterTestingT(t)
+
+	var body string
+	result := `{"action_results":[true],"status":1}`
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Write([]byte(result))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"unarchive", "42"})
+	Expect(err).To(BeNil())
+
+	Expect(commandUnarchive(arguments, client)).To(Succeed())
+	Expect(body).To(ContainSubstring(`{"action":"readd","item_id":"42"}`))
+	Expect(out.String()).To(Equal("Unarchived 42\n"))
+
+	result = `{"action_results":[false],"status":1}`
+	Expect(commandUnarchive(arguments, client)).To(MatchError("readd failed for item 42"))
+}
+
+func TestCommandTag(t *testing.T) {
+	RegisterTestingT(t)
+
+	var body string
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Write([]byte(`{"action_results":[true],"status":1}`))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"tag", "add", "42", "go, read later,"})
+	Expect(err).To(BeNil())
+
+	Expect(commandTag(arguments, client)).To(Succeed())
+	Expect(body).To(ContainSubstring(`{"action":"tags_add","item_id":"42","tags":"go,read later"}`))
+	Expect(out.String()).To(Equal("Added tags to 42\n"))
+
+	arguments, err = parseArguments([]string{"tag", "remove", "42", "go"})
+	Expect(err).To(BeNil())
+
+	Expect(commandTag(arguments, client)).To(Succeed())
+	Expect(body).To(ContainSubstring(`{"action":"tags_remove","item_id":"42","tags":"go"}`))
+}
+
+func TestCommandTagInvalidItemID(t *testing.T) {
+	RegisterTestingT(t)
+
+	requests := 0
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	})
+	defer ts.Close()
+
+	arguments, err := parseArguments([]string{"tag", "add", "forty-two", "go"})
+	Expect(err).To(BeNil())
+
+	Expect(commandTag(arguments, client)).To(MatchError(`invalid item id "forty-two"`))
+	Expect(requests).To(Equal(0))
+}
+
+func TestCommandRenameTag(t *testing.T) {
+	RegisterTestingT(t)
+
+	var body string
+	result := `{"action_results":[true],"status":1}`
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Write([]byte(result))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"rename-tag", "golang", "go"})
+	Expect(err).To(BeNil())
+
+	Expect(commandRenameTag(arguments, client)).To(Succeed())
+	Expect(body).To(ContainSubstring(`{"action":"tag_rename","old_tag":"golang","new_tag":"go"}`))
+	Expect(out.String()).To(Equal("Renamed tag \"golang\" to \"go\"\n"))
+
+	result = `{"action_results":[false],"status":1}`
+	Expect(commandRenameTag(arguments, client)).To(MatchError(`renaming tag "golang" failed`))
+}
+
+func TestCommandArchive(t *testing.T) {
+	RegisterTestingT(t)
+
+	var body string
+	requests := 0
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Write([]byte(`{"action_results":[true,false],"status":1}`))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"archive", "1", "2"})
+	Expect(err).To(BeNil())
+
+	Expect(commandArchive(arguments, client)).To(MatchError("archive failed for item 2"))
+	Expect(requests).To(Equal(1))
+	Expect(body).To(ContainSubstring(`"actions":[{"action":"archive","item_id":"1"},{"action":"archive","item_id":"2"}]`))
+	Expect(out.String()).To(Equal("Archived 1\n"))
+}
+
+func TestCommandArchiveFromStdin(t *testing.T) {
+	RegisterTestingT(t)
+
+	var body string
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Write([]byte(`{"action_results":[true,true,true],"status":1}`))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+	defer func() { stdin = os.Stdin }()
+
+	stdin = strings.NewReader("1\n\n  2\n3\n")
+
+	arguments, err := parseArguments([]string{"archive", "-"})
+	Expect(err).To(BeNil())
+
+	Expect(commandArchive(arguments, client)).To(Succeed())
+	Expect(body).To(ContainSubstring(`"actions":[{"action":"archive","item_id":"1"},{"action":"archive","item_id":"2"},{"action":"archive","item_id":"3"}]`))
+	Expect(out.String()).To(Equal("Archived 1\nArchived 2\nArchived 3\n"))
+}
+
+func TestCommandArchiveEmptyStdin(t *testing.T) {
+	RegisterTestingT(t)
+
+	requests := 0
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"action_results":[],"status":1}`))
+	})
+	defer ts.Close()
+	defer func() { stdin = os.Stdin }()
+
+	arguments, err := parseArguments([]string{"archive", "-"})
+	Expect(err).To(BeNil())
+
+	for _, input := range []string{"", "\n  \n\n"} {
+		stdin = strings.NewReader(input)
+		Expect(commandArchive(arguments, client)).To(MatchError("no item ids given"))
+	}
+	Expect(requests).To(Equal(0))
+}
+
+func TestCommandDeleteFromStdin(t *testing.T) {
+	RegisterTestingT(t)
+
+	requests := 0
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"action_results":[true,true],"status":1}`))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+	defer func() { stdin = os.Stdin }()
+
+	arguments, err := parseArguments([]string{"delete", "-"})
+	Expect(err).To(BeNil())
+
+	stdin = strings.NewReader("1\n2\n")
+	Expect(commandDelete(arguments, client)).To(MatchError(ContainSubstring("--force")))
+	Expect(requests).To(Equal(0))
+
+	arguments, err = parseArguments([]string{"delete", "-", "--force"})
+	Expect(err).To(BeNil())
+
+	stdin = strings.NewReader("1\n2\n")
+	Expect(commandDelete(arguments, client)).To(Succeed())
+	Expect(out.String()).To(Equal("Deleted 1\nDeleted 2\n"))
+	Expect(requests).To(Equal(1))
+}
+
+func TestReadItemIDs(t *testing.T) {
+	RegisterTestingT(t)
+
+	itemIDs, err := readItemIDs(strings.NewReader("\n10\n 20 \n\n"))
+	Expect(err).To(BeNil())
+	Expect(itemIDs).To(Equal([]int{10, 20}))
+
+	_, err = readItemIDs(strings.NewReader("10\n\nabc\n"))
+	Expect(err).To(MatchError(`line 3: invalid item id "abc"`))
+}
+
+func TestLoadConsumerKey(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	consumerKeyPath := filepath.Join(dir, "consumer_key")
+	Expect(ioutil.WriteFile(consumerKeyPath, []byte("file-key\n"), 0600)).To(Succeed())
+
+	consumerKey, err := loadConsumerKey("env-key", consumerKeyPath)
+	Expect(err).To(BeNil())
+	Expect(consumerKey).To(Equal("env-key"))
+
+	consumerKey, err = loadConsumerKey("", consumerKeyPath)
+	Expect(err).To(BeNil())
+	Expect(consumerKey).To(Equal("file-key"))
+}
+
+func TestGetConsumerKeyPrecedence(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	defer func(dir string) { configDir = dir }(configDir)
+	defer os.Unsetenv("POCKET_CONSUMER_KEY")
+	defer func() { stdin = os.Stdin }()
+
+	configDir = dir
+	flag, err := parseArguments([]string{"list", "--consumer-key", "flag-key"})
+	Expect(err).To(BeNil())
+	noFlag, err := parseArguments([]string{"list"})
+	Expect(err).To(BeNil())
+
+	stdin = strings.NewReader("typed-key\n")
+	Expect(getConsumerKey(noFlag)).To(Equal("typed-key"))
+
+	Expect(ioutil.WriteFile(filepath.Join(dir, "consumer_key"), []byte("file-key\n"), 0600)).To(Succeed())
+	Expect(getConsumerKey(noFlag)).To(Equal("file-key"))
+
+	os.Setenv("POCKET_CONSUMER_KEY", "env-key")
+	Expect(getConsumerKey(noFlag)).To(Equal("env-key"))
+	Expect(getConsumerKey(flag)).To(Equal("flag-key"))
+
+	// The key from the flag is not saved.
+	saved, err := ioutil.ReadFile(filepath.Join(dir, "consumer_key"))
+	Expect(err).To(BeNil())
+	Expect(string(saved)).To(Equal("file-key\n"))
+}
+
+func TestLoadConsumerKeyPrompts(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	defer func() { stdin = os.Stdin }()
+
+	stdin = strings.NewReader("typed-key\n")
+
+	consumerKeyPath := filepath.Join(dir, "consumer_key")
+	consumerKey, err := loadConsumerKey("", consumerKeyPath)
+	Expect(err).To(BeNil())
+	Expect(consumerKey).To(Equal("typed-key"))
+
+	saved, err := ioutil.ReadFile(consumerKeyPath)
+	Expect(err).To(BeNil())
+	Expect(string(saved)).To(Equal("typed-key"))
+}
+
+func TestQuietSuppressesLogs(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	defer func() { stdin = os.Stdin }()
+	defer logger.SetOutput(os.Stderr)
+
+	logged := &bytes.Buffer{}
+	logger.SetOutput(logged)
+	stdin = strings.NewReader("typed-key\n")
+
+	arguments, err := parseArguments([]string{"list", "--quiet"})
+	Expect(err).To(BeNil())
+	verbose, err := setupLogging(arguments)
+	Expect(err).To(BeNil())
+	Expect(verbose).To(BeFalse())
+
+	_, err = loadConsumerKey("", filepath.Join(dir, "consumer_key"))
+	Expect(err).To(BeNil())
+	Expect(logged.String()).NotTo(ContainSubstring("Can't get consumer key"))
+}
+
+func TestSetupLoggingQuietAndVerbose(t *testing.T) {
+	RegisterTestingT(t)
+
+	arguments, err := parseArguments([]string{"list", "--quiet", "--verbose"})
+	Expect(err).To(BeNil())
+	_, err = setupLogging(arguments)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestVerboseLogsRequests(t *testing.T) {
+	RegisterTestingT(t)
+
+	defer logger.SetOutput(os.Stderr)
+	logged := &bytes.Buffer{}
+	logger.SetOutput(logged)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testList))
+	}))
+	defer ts.Close()
+
+	client := api.NewClientWithHTTP("consumer", "token", &http.Client{Transport: loggingTransport{http.DefaultTransport}})
+	client.BaseURL = ts.URL + "/v3"
+
+	_, err := client.Retrieve(&api.RetrieveOption{})
+	Expect(err).To(BeNil())
+	Expect(logged.String()).To(ContainSubstring("POST " + ts.URL + "/v3/get: 200 OK"))
+}
+
+func TestLoadAccessTokenFromEnv(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	authFile := filepath.Join(dir, "auth.json")
+	obtained := false
+	obtain := func() (*auth.Authorization, error) {
+		obtained = true
+		return &auth.Authorization{AccessToken: "oauth-token"}, nil
+	}
+
+	accessToken, err := loadAccessToken("env-token", authFile, obtain)
+	Expect(err).To(BeNil())
+	Expect(accessToken.AccessToken).To(Equal("env-token"))
+	Expect(obtained).To(BeFalse())
+	_, err = os.Stat(authFile)
+	Expect(os.IsNotExist(err)).To(BeTrue())
+
+	accessToken, err = loadAccessToken("", authFile, obtain)
+	Expect(err).To(BeNil())
+	Expect(accessToken.AccessToken).To(Equal("oauth-token"))
+	Expect(obtained).To(BeTrue())
+
+	obtained = false
+	accessToken, err = loadAccessToken("", authFile, obtain)
+	Expect(err).To(BeNil())
+	Expect(accessToken.AccessToken).To(Equal("oauth-token"))
+	Expect(obtained).To(BeFalse())
+}
+
+func TestSetupConfigDir(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	defer func(dir string) { configDir = dir }(configDir)
+
+	custom := filepath.Join(dir, "work")
+	arguments, err := parseArguments([]string{"list", "--config-dir", custom})
+	Expect(err).To(BeNil())
+
+	configDir, err = setupConfigDir(arguments)
+	Expect(err).To(BeNil())
+	Expect(configDir).To(Equal(custom))
+
+	info, err := os.Stat(custom)
+	Expect(err).To(BeNil())
+	Expect(info.IsDir()).To(BeTrue())
+
+	Expect(saveJSONToFile(filepath.Join(custom, "auth.json"), &auth.Authorization{AccessToken: "work-token"})).To(Succeed())
+
+	os.Unsetenv("POCKET_ACCESS_TOKEN")
+	accessToken, err := restoreAccessToken("consumer", &authOptions{})
+	Expect(err).To(BeNil())
+	Expect(accessToken.AccessToken).To(Equal("work-token"))
+}
+
+func TestSetupConfigDirFromEnv(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	defer os.Unsetenv("POCKET_CONFIG_DIR")
+
+	os.Setenv("POCKET_CONFIG_DIR", filepath.Join(dir, "env"))
+
+	arguments, err := parseArguments([]string{"list"})
+	Expect(err).To(BeNil())
+
+	resolved, err := setupConfigDir(arguments)
+	Expect(err).To(BeNil())
+	Expect(resolved).To(Equal(filepath.Join(dir, "env")))
+
+	arguments, err = parseArguments([]string{"list", "--config-dir", filepath.Join(dir, "flag")})
+	Expect(err).To(BeNil())
+
+	resolved, err = setupConfigDir(arguments)
+	Expect(err).To(BeNil())
+	Expect(resolved).To(Equal(filepath.Join(dir, "flag")))
+}
+
+func TestSetupConfigDirProfiles(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	defer func(dir string) { configDir = dir }(configDir)
+
+	os.Unsetenv("POCKET_CONSUMER_KEY")
+	os.Unsetenv("POCKET_ACCESS_TOKEN")
+
+	for _, profile := range []string{"work", "personal"} {
+		arguments, err := parseArguments([]string{"list", "--config-dir", dir, "--profile", profile})
+		Expect(err).To(BeNil())
+
+		configDir, err = setupConfigDir(arguments)
+		Expect(err).To(BeNil())
+		Expect(configDir).To(Equal(filepath.Join(dir, profile)))
+
+		Expect(ioutil.WriteFile(filepath.Join(configDir, "consumer_key"), []byte(profile+"-key"), 0600)).To(Succeed())
+		Expect(saveJSONToFile(filepath.Join(configDir, "auth.json"), &auth.Authorization{AccessToken: profile + "-token"})).To(Succeed())
+	}
+
+	for _, profile := range []string{"work", "personal"} {
+		arguments, err := parseArguments([]string{"list", "--config-dir", dir, "--profile", profile})
+		Expect(err).To(BeNil())
+
+		configDir, err = setupConfigDir(arguments)
+		Expect(err).To(BeNil())
+
+		Expect(getConsumerKey(arguments)).To(Equal(profile + "-key"))
+
+		accessToken, err := restoreAccessToken(profile+"-key", &authOptions{})
+		Expect(err).To(BeNil())
+		Expect(accessToken.AccessToken).To(Equal(profile + "-token"))
+	}
+
+	arguments, err := parseArguments([]string{"list", "--config-dir", dir, "--profile", "../escape"})
+	Expect(err).To(BeNil())
+
+	_, err = setupConfigDir(arguments)
+	Expect(err).To(MatchError(`invalid profile name "../escape"`))
+}
+
+func TestCommandGet(t *testing.T) {
+	RegisterTestingT(t)
+
+	var options map[string]interface{}
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		options = map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&options)
+		if options["offset"] != nil {
+			w.Write([]byte(`{"list":{},"status":2}`))
+			return
+		}
+		w.Write([]byte(testList))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"get", "2", "--json"})
+	Expect(err).To(BeNil())
+
+	Expect(commandGet(arguments, client)).To(Succeed())
+
+	var item api.Item
+	Expect(json.Unmarshal(out.Bytes(), &item)).To(Succeed())
+	Expect(item.ItemID).To(Equal(int64(2)))
+	Expect(item.ResolvedTitle).To(Equal("Second, with a comma"))
+	Expect(options["state"]).To(Equal("all"))
+	Expect(options["detailType"]).To(Equal("complete"))
+
+	out.Reset()
+	arguments, err = parseArguments([]string{"get", "1"})
+	Expect(err).To(BeNil())
+
+	Expect(commandGet(arguments, client)).To(Succeed())
+	Expect(out.String()).To(ContainSubstring("Title:    First\n"))
+	Expect(out.String()).To(ContainSubstring("URL:      https://example.com/1\n"))
+	Expect(out.String()).To(ContainSubstring("Added:    2020-01-01 00:00:00\n"))
+
+	out.Reset()
+	arguments, err = parseArguments([]string{"get", "1", "--format", "{{.ItemID}}"})
+	Expect(err).To(BeNil())
+
+	Expect(commandGet(arguments, client)).To(Succeed())
+	Expect(out.String()).To(Equal("1\n"))
+
+	arguments, err = parseArguments([]string{"get", "3"})
+	Expect(err).To(BeNil())
+
+	Expect(commandGet(arguments, client)).To(MatchError("item 3 not found"))
+}
+
+func TestCommandCount(t *testing.T) {
+	RegisterTestingT(t)
+
+	pages := []string{
+		`{"list":{"1":{"item_id":"1","sort_id":0},"2":{"item_id":"2","sort_id":1}},"status":1}`,
+		`{"list":{"3":{"item_id":"3","sort_id":0}},"status":1}`,
+		`{"list":{},"status":2}`,
+	}
+	var offsets []float64
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		var options struct {
+			Offset float64 `json:"offset"`
+			Count  float64 `json:"count"`
+			Tag    string  `json:"tag"`
+		}
+		json.NewDecoder(r.Body).Decode(&options)
+		Expect(options.Tag).To(Equal("go"))
+		offsets = append(offsets, options.Offset)
+		w.Write([]byte(pages[int(options.Offset/options.Count)]))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"count", "--tag", "go", "--state", "all"})
+	Expect(err).To(BeNil())
+
+	Expect(commandCount(arguments, client)).To(Succeed())
+	Expect(out.String()).To(Equal("3\n"))
+	Expect(offsets).To(Equal([]float64{0, 100, 200}))
+}
+
+func TestCommandListTagsAll(t *testing.T) {
+	RegisterTestingT(t)
+
+	var options map[string]interface{}
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&options)
+		w.Write([]byte(`{"list":{
+			"1":{"item_id":"1","sort_id":0,"tags":{"go":{"tag":"go"}}},
+			"2":{"item_id":"2","sort_id":1,"tags":{"go":{"tag":"go"},"concurrency":{"tag":"concurrency"}}},
+			"3":{"item_id":"3","sort_id":2,"tags":{"concurrency":{"tag":"concurrency"},"machine learning":{"tag":"machine learning"}}},
+			"4":{"item_id":"4","sort_id":3,"tags":{"go":{"tag":"go"},"concurrency":{"tag":"concurrency"},"machine learning":{"tag":"machine learning"}}},
+			"5":{"item_id":"5","sort_id":4,"tags":[]}
+		},"status":1}`))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"list", "--tags-all", "go,concurrency", "--format", "{{.ItemID}}"})
+	Expect(err).To(BeNil())
+
+	Expect(commandList(arguments, client)).To(Succeed())
+	Expect(out.String()).To(Equal("2\n4\n"))
+	Expect(options["detailType"]).To(Equal("complete"))
+
+	out.Reset()
+	arguments, err = parseArguments([]string{"list", "--tags-all", "machine learning, concurrency", "--format", "{{.ItemID}}"})
+	Expect(err).To(BeNil())
+
+	Expect(commandList(arguments, client)).To(Succeed())
+	Expect(out.String()).To(Equal("3\n4\n"))
+}
+
+func TestCommandListMinWords(t *testing.T) {
+	RegisterTestingT(t)
+
+	var options map[string]interface{}
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&options)
+		w.Write([]byte(`{"list":{
+			"1":{"item_id":"1","sort_id":0,"word_count":"2001"},
+			"2":{"item_id":"2","sort_id":1,"word_count":"2000"},
+			"3":{"item_id":"3","sort_id":2,"word_count":"0"},
+			"4":{"item_id":"4","sort_id":3}
+		},"status":1}`))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"list", "--min-words", "2000", "--format", "{{.ItemID}}"})
+	Expect(err).To(BeNil())
+
+	Expect(commandList(arguments, client)).To(Succeed())
+	Expect(out.String()).To(Equal("1\n"))
+	Expect(options["detailType"]).To(Equal("complete"))
+
+	arguments, err = parseArguments([]string{"list", "--min-words", "many"})
+	Expect(err).To(BeNil())
+	Expect(commandList(arguments, client)).To(MatchError(ContainSubstring("--min-words must be a non-negative integer")))
+}
+
+func TestCommandListUntagged(t *testing.T) {
+	RegisterTestingT(t)
+
+	var options map[string]interface{}
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&options)
+		w.Write([]byte(`{"list":{
+			"1":{"item_id":"1","sort_id":0,"tags":{"go":{"tag":"go"}}},
+			"2":{"item_id":"2","sort_id":1,"tags":[]},
+			"3":{"item_id":"3","sort_id":2}
+		},"status":1}`))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"list", "--untagged", "--format", "{{.ItemID}}"})
+	Expect(err).To(BeNil())
+
+	Expect(commandList(arguments, client)).To(Succeed())
+	Expect(out.String()).To(Equal("2\n3\n"))
+	Expect(options["detailType"]).To(Equal("complete"))
+
+	_, err = docopt.Parse(fmt.Sprintf(usage, getFields()), []string{"list", "--untagged", "--tag", "go"}, true, version, false, false)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestCommandTags(t *testing.T) {
+	RegisterTestingT(t)
+
+	s := apitest.NewServer()
+	defer s.Close()
+	client := s.Client()
+
+	_, err := client.AddBatch([]*api.AddOption{
+		{URL: "https://example.com/1", Tags: "go,cli"},
+		{URL: "https://example.com/2", Tags: "go,concurrency"},
+		{URL: "https://example.com/3", Tags: "concurrency,go,reading"},
+		{URL: "https://example.com/4"},
+	})
+	Expect(err).To(BeNil())
+	_, err = client.Modify(api.NewArchiveAction(3))
+	Expect(err).To(BeNil())
+
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"tags"})
+	Expect(err).To(BeNil())
+
+	Expect(commandTags(arguments, client)).To(Succeed())
+	Expect(out.String()).To(Equal("3\tgo\n2\tconcurrency\n1\tcli\n1\treading\n"))
+
+	out.Reset()
+	arguments, err = parseArguments([]string{"tags", "--sort", "name"})
+	Expect(err).To(BeNil())
+
+	Expect(commandTags(arguments, client)).To(Succeed())
+	Expect(out.String()).To(Equal("1\tcli\n2\tconcurrency\n3\tgo\n1\treading\n"))
+
+	arguments, err = parseArguments([]string{"tags", "--sort", "newest"})
+	Expect(err).To(BeNil())
+
+	Expect(commandTags(arguments, client)).NotTo(Succeed())
+}
+
+func TestCommandListDomains(t *testing.T) {
+	RegisterTestingT(t)
+
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"list":{
+			"1":{"item_id":"1","sort_id":0,"resolved_url":"https://www.a.com/1"},
+			"2":{"item_id":"2","sort_id":1,"resolved_url":"https://b.com/2"},
+			"3":{"item_id":"3","sort_id":2,"resolved_url":"https://c.com/3"},
+			"4":{"item_id":"4","sort_id":3,"given_url":"https://a.com/4"}
+		},"status":1}`))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"list", "--domains", "a.com, www.b.com", "--format", "{{.ItemID}}"})
+	Expect(err).To(BeNil())
+
+	Expect(commandList(arguments, client)).To(Succeed())
+	Expect(out.String()).To(Equal("1\n2\n4\n"))
+}
+
+func TestRunWithReauthorization(t *testing.T) {
+	RegisterTestingT(t)
+
+	var tokens []string
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			AccessToken string `json:"access_token"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		tokens = append(tokens, body.AccessToken)
+		if body.AccessToken != "new" {
+			w.Header().Set("X-Error-Code", "107")
+			w.WriteHeader(401)
+			return
+		}
+		w.Write([]byte(testList))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"list", "--format", "{{.ItemID}}"})
+	Expect(err).To(BeNil())
+
+	reauthorized := 0
+	err = runWithReauthorization(arguments, client, func() (*auth.Authorization, error) {
+		reauthorized++
+		return &auth.Authorization{AccessToken: "new"}, nil
+	})
+	Expect(err).To(BeNil())
+	Expect(reauthorized).To(Equal(1))
+	Expect(tokens).To(Equal([]string{"token", "new"}))
+	Expect(out.String()).To(Equal("1\n2\n"))
+}
+
+func TestRunWithReauthorizationOnlyOnce(t *testing.T) {
+	RegisterTestingT(t)
+
+	requests := 0
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(401)
+	})
+	defer ts.Close()
+
+	arguments, err := parseArguments([]string{"archive", "1"})
+	Expect(err).To(BeNil())
+
+	reauthorized := 0
+	err = runWithReauthorization(arguments, client, func() (*auth.Authorization, error) {
+		reauthorized++
+		return &auth.Authorization{AccessToken: "still-revoked"}, nil
+	})
+	Expect(errors.Is(err, api.ErrUnauthorized)).To(BeTrue())
+	Expect(reauthorized).To(Equal(1))
+	Expect(requests).To(Equal(2))
+}
+
+func TestRunWithReauthorizationReadsStdinOnce(t *testing.T) {
+	RegisterTestingT(t)
+
+	var bodies []string
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if len(bodies)%2 == 1 {
+			w.Header().Set("X-Error-Code", "107")
+			w.WriteHeader(401)
+			return
+		}
+		w.Write([]byte(`{"action_results":[true,true],"status":1}`))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+	defer func() { stdin = os.Stdin }()
+
+	reauthorize := func() (*auth.Authorization, error) {
+		return &auth.Authorization{AccessToken: "new"}, nil
+	}
+
+	arguments, err := parseArguments([]string{"delete", "5"})
+	Expect(err).To(BeNil())
+	stdin = strings.NewReader("y\n")
+
+	Expect(runWithReauthorization(arguments, client, reauthorize)).To(Succeed())
+	Expect(out.String()).To(Equal("Permanently delete item 5? [y/N] Deleted 5\n"))
+	Expect(bodies).To(HaveLen(2))
+	Expect(bodies[1]).To(ContainSubstring(`"actions":[{"action":"delete","item_id":"5"}]`))
+
+	out.Reset()
+	arguments, err = parseArguments([]string{"archive", "-"})
+	Expect(err).To(BeNil())
+	stdin = strings.NewReader("1\n2\n")
+
+	Expect(runWithReauthorization(arguments, client, reauthorize)).To(Succeed())
+	Expect(out.String()).To(Equal("Archived 1\nArchived 2\n"))
+	Expect(bodies).To(HaveLen(4))
+	Expect(bodies[3]).To(ContainSubstring(`"actions":[{"action":"archive","item_id":"1"},{"action":"archive","item_id":"2"}]`))
+}
+
+func TestMainFailingCommandExits(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Error", "Pocket server issue")
+		w.WriteHeader(503)
+	}))
+	defer ts.Close()
+
+	defer func(origin string, args []string, dir string) {
+		api.Origin = origin
+		os.Args = args
+		configDir = dir
+		stderr = os.Stderr
+		exit = os.Exit
+	}(api.Origin, os.Args, configDir)
+	defer os.Unsetenv("POCKET_CONSUMER_KEY")
+	defer os.Unsetenv("POCKET_ACCESS_TOKEN")
+
+	api.Origin = ts.URL
+	os.Args = []string{"pocket", "list", "--config-dir", dir}
+	os.Setenv("POCKET_CONSUMER_KEY", "consumer")
+	os.Setenv("POCKET_ACCESS_TOKEN", "token")
+
+	errOut := &bytes.Buffer{}
+	stderr = errOut
+	status := -1
+	exit = func(code int) {
+		status = code
+		runtime.Goexit()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		main()
+	}()
+	<-done
+
+	Expect(status).To(Equal(1))
+	Expect(errOut.String()).To(Equal("got response 503; X-Error-Code=[0] X-Error=[Pocket server issue]\n"))
+}
+
+func TestMainEnvironmentTokenRejected(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("X-Error-Code", "107")
+		w.WriteHeader(401)
+	}))
+	defer ts.Close()
+
+	defer func(origin string, args []string, dir string) {
+		api.Origin = origin
+		os.Args = args
+		configDir = dir
+		stderr = os.Stderr
+		exit = os.Exit
+	}(api.Origin, os.Args, configDir)
+	defer os.Unsetenv("POCKET_CONSUMER_KEY")
+	defer os.Unsetenv("POCKET_ACCESS_TOKEN")
+
+	api.Origin = ts.URL
+	os.Args = []string{"pocket", "list", "--config-dir", dir}
+	os.Setenv("POCKET_CONSUMER_KEY", "consumer")
+	os.Setenv("POCKET_ACCESS_TOKEN", "revoked")
+
+	errOut := &bytes.Buffer{}
+	stderr = errOut
+	status := -1
+	exit = func(code int) {
+		status = code
+		runtime.Goexit()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		main()
+	}()
+	<-done
+
+	// No OAuth flow is started, and nothing is saved to auth.json.
+	Expect(status).To(Equal(1))
+	Expect(requests).To(Equal(1))
+	Expect(errOut.String()).To(HaveSuffix("; replace the access token in POCKET_ACCESS_TOKEN\n"))
+	Expect(filepath.Join(dir, "auth.json")).NotTo(BeAnExistingFile())
+}
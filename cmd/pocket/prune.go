@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/bvp/go-pocket/api"
+)
+
+// defaultPruneWorkers is how many URLs prune checks at once unless --workers
+// says otherwise.
+const defaultPruneWorkers = 4
+
+// commandPrune checks the URL of every item, and archives the items whose page
+// is gone, or deletes them with --delete. Archiving only checks unread items.
+func commandPrune(arguments map[string]interface{}, client *api.Client) error {
+	workers, err := nonNegativeArgument(arguments, "--workers")
+	if err != nil {
+		return err
+	}
+	if workers == 0 {
+		workers = defaultPruneWorkers
+	}
+
+	archive := true
+	if del, ok := arguments["--delete"].(bool); ok && del {
+		archive = false
+	}
+
+	options := &api.RetrieveOption{State: api.StateUnread}
+	if !archive {
+		options.State = api.StateAll
+	}
+	items, err := client.RetrieveAll(options)
+	if err != nil {
+		return err
+	}
+
+	statuses := checkURLs(client, items, workers)
+
+	actions := []*api.Action{}
+	for i, item := range items {
+		if status := statuses[i]; status == http.StatusNotFound || status == http.StatusGone {
+			fmt.Fprintf(stdout, "Dead link (%d) %d %s\n", status, item.ItemID, item.URL())
+			if archive {
+				actions = append(actions, api.NewArchiveAction(int(item.ItemID)))
+			} else {
+				actions = append(actions, api.NewDeleteAction(int(item.ItemID)))
+			}
+		}
+	}
+
+	if len(actions) == 0 {
+		fmt.Fprintf(stdout, "No dead links in %d items\n", len(items))
+		return nil
+	}
+
+	if archive {
+		return performActions(client, actions, "Archived")
+	}
+	return performActions(client, actions, "Deleted")
+}
+
+// checkURLs checks the URLs of items with as many workers, and returns the
+// statuses aligned by index with items. The URLs which could not be checked
+// are reported on stderr, and have a status of zero.
+func checkURLs(client *api.Client, items []api.Item, workers int) []int {
+	statuses := make([]int, len(items))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				_, status, err := client.CheckURL(items[i].URL())
+				if err != nil {
+					mu.Lock()
+					fmt.Fprintf(stderr, "Could not check %s: %v\n", items[i].URL(), err)
+					mu.Unlock()
+					continue
+				}
+				statuses[i] = status
+			}
+		}()
+	}
+
+	for i := range items {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return statuses
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/bvp/go-pocket/api"
+	"github.com/bvp/go-pocket/api/apitest"
+	. "github.com/onsi/gomega"
+)
+
+// pruneServers returns a site with a page at /ok, /gone answering 410 and
+// nothing else, and a Pocket server with items linking to /ok, /missing and
+// /gone, from newest to oldest.
+func pruneServers() (*httptest.Server, *apitest.Server) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+		case "/gone":
+			w.WriteHeader(http.StatusGone)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	server := apitest.NewServer()
+	_, err := server.Client().ModifyBatch(
+		addAction(&api.AddOption{URL: site.URL + "/ok", Time: 1577836900}),
+		addAction(&api.AddOption{URL: site.URL + "/missing", Time: 1577836800}),
+		addAction(&api.AddOption{URL: site.URL + "/gone", Time: 1577836700}),
+	)
+	Expect(err).To(BeNil())
+
+	return site, server
+}
+
+func TestCommandPrune(t *testing.T) {
+	RegisterTestingT(t)
+
+	site, server := pruneServers()
+	defer site.Close()
+	defer server.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"prune", "--workers", "2"})
+	Expect(err).To(BeNil())
+
+	Expect(commandPrune(arguments, server.Client())).To(Succeed())
+	Expect(out.String()).To(Equal("Dead link (404) 2 " + site.URL + "/missing\n" +
+		"Dead link (410) 3 " + site.URL + "/gone\n" +
+		"Archived 2\nArchived 3\n"))
+
+	items := server.Items()
+	Expect(items[0].Status).To(Equal(api.ItemStatusUnread))
+	Expect(items[1].Status).To(Equal(api.ItemStatus(api.ItemStatusArchived)))
+	Expect(items[2].Status).To(Equal(api.ItemStatus(api.ItemStatusArchived)))
+
+	// Archived items are not checked again.
+	out.Reset()
+	Expect(commandPrune(arguments, server.Client())).To(Succeed())
+	Expect(out.String()).To(Equal("No dead links in 1 items\n"))
+}
+
+func TestCommandPruneDelete(t *testing.T) {
+	RegisterTestingT(t)
+
+	site, server := pruneServers()
+	defer site.Close()
+	defer server.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"prune", "--delete"})
+	Expect(err).To(BeNil())
+
+	Expect(commandPrune(arguments, server.Client())).To(Succeed())
+	Expect(out.String()).To(HaveSuffix("Deleted 2\nDeleted 3\n"))
+	Expect(server.Items()).To(HaveLen(1))
+}
+
+func TestCommandPruneUnreachable(t *testing.T) {
+	RegisterTestingT(t)
+
+	site, server := pruneServers()
+	defer server.Close()
+	site.Close()
+	out := captureStdout()
+	defer resetStdout()
+	defer func() { stderr = os.Stderr }()
+
+	errOut := &bytes.Buffer{}
+	stderr = errOut
+
+	arguments, err := parseArguments([]string{"prune"})
+	Expect(err).To(BeNil())
+
+	// Links which cannot be checked are not taken for dead.
+	Expect(commandPrune(arguments, server.Client())).To(Succeed())
+	Expect(out.String()).To(Equal("No dead links in 3 items\n"))
+	Expect(errOut.String()).To(ContainSubstring("Could not check " + site.URL + "/ok"))
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/bvp/go-pocket/api"
+)
+
+const (
+	ansiBold   = "\x1b[1m"
+	ansiDim    = "\x1b[2m"
+	ansiYellow = "\x1b[33m"
+	ansiReset  = "\x1b[0m"
+)
+
+// commandSearch lists the items matching the query like list --search does,
+// with the matches in titles and excerpts shown in bold on a terminal.
+func commandSearch(arguments map[string]interface{}, client *api.Client) error {
+	query := arguments["<query>"].(string)
+	if strings.TrimSpace(query) == "" {
+		return fmt.Errorf("the search query is empty")
+	}
+
+	options, err := listOptions(arguments)
+	if err != nil {
+		return err
+	}
+	options.Search = query
+
+	res, err := client.Retrieve(options)
+	if err != nil {
+		return err
+	}
+
+	highlight := func(s string) string { return s }
+	if useColor(stdout) {
+		match := regexp.MustCompile("(?i)" + regexp.QuoteMeta(query))
+		highlight = func(s string) string {
+			return match.ReplaceAllString(s, ansiBold+"$0"+ansiReset)
+		}
+	}
+
+	for _, item := range res.Items() {
+		fmt.Fprintf(stdout, "[%9d] %s <%s>\n", item.ItemID, highlight(item.Title()), item.URL())
+		if excerpt := strings.TrimSpace(item.Excerpt); excerpt != "" {
+			fmt.Fprintf(stdout, "            %s\n", highlight(excerpt))
+		}
+	}
+
+	return nil
+}
+
+// useColor reports whether output to w may use ANSI escape codes: only when
+// it is a terminal, and NO_COLOR (https://no-color.org) is not set.
+func useColor(w io.Writer) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	return isTerminal(w)
+}
+
+// isTerminal reports whether w writes to a terminal rather than to a file or
+// a pipe.
+var isTerminal = func(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCommandSearch(t *testing.T) {
+	RegisterTestingT(t)
+
+	var body map[string]interface{}
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+		w.Write([]byte(`{"list":{
+			"1":{"item_id":"1","given_title":"Learning Go","given_url":"https://example.com/1","excerpt":"Why go is simple","sort_id":0},
+			"2":{"item_id":"2","given_title":"Other","given_url":"https://go.example.com/2","sort_id":1}
+		},"status":1}`))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"search", "go", "--state", "all"})
+	Expect(err).To(BeNil())
+
+	Expect(commandSearch(arguments, client)).To(Succeed())
+	Expect(body["search"]).To(Equal("go"))
+	Expect(body["state"]).To(Equal("all"))
+
+	// Nothing is highlighted when stdout is not a terminal.
+	Expect(out.String()).NotTo(ContainSubstring("\x1b"))
+	Expect(out.String()).To(Equal(`[        1] Learning Go <https://example.com/1>
+            Why go is simple
+[        2] Other <https://go.example.com/2>
+`))
+}
+
+// fakeTerminal makes every writer look like a terminal until the returned
+// function is called.
+func fakeTerminal() func() {
+	previous := isTerminal
+	isTerminal = func(io.Writer) bool { return true }
+	return func() { isTerminal = previous }
+}
+
+func TestCommandSearchHighlights(t *testing.T) {
+	RegisterTestingT(t)
+
+	defer fakeTerminal()()
+
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"list":{"1":{"item_id":"1","given_title":"Learning Go","given_url":"https://example.com/1"}},"status":1}`))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"search", "go"})
+	Expect(err).To(BeNil())
+
+	Expect(commandSearch(arguments, client)).To(Succeed())
+	Expect(out.String()).To(Equal("[        1] Learning \x1b[1mGo\x1b[0m <https://example.com/1>\n"))
+}
+
+func TestCommandListColor(t *testing.T) {
+	RegisterTestingT(t)
+
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"list":{
+			"1":{"item_id":"1","given_title":"First","given_url":"https://example.com/1","favorite":"1","sort_id":0},
+			"2":{"item_id":"2","given_title":"Second","given_url":"https://example.com/2","sort_id":1}
+		},"status":1}`))
+	})
+	defer ts.Close()
+	out := captureStdout()
+	defer resetStdout()
+
+	arguments, err := parseArguments([]string{"list"})
+	Expect(err).To(BeNil())
+
+	Expect(commandList(arguments, client)).To(Succeed())
+	plain := out.String()
+	Expect(plain).To(Equal("[        1] First <https://example.com/1>\n[        2] Second <https://example.com/2>\n"))
+
+	restore := fakeTerminal()
+	defer restore()
+
+	out.Reset()
+	Expect(commandList(arguments, client)).To(Succeed())
+	Expect(out.String()).To(Equal("\x1b[2m[        1]\x1b[0m \x1b[33mFirst\x1b[0m <https://example.com/1>\n" +
+		"\x1b[2m[        2]\x1b[0m Second <https://example.com/2>\n"))
+
+	os.Setenv("NO_COLOR", "")
+	defer os.Unsetenv("NO_COLOR")
+
+	out.Reset()
+	Expect(commandList(arguments, client)).To(Succeed())
+	Expect(out.String()).To(Equal(plain))
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"text/template"
+
+	"github.com/bvp/go-pocket/api"
+)
+
+const maxFilename = 127
+
+// hashLength is how many hex digits of the URL hash are appended to titles to
+// keep the filenames of items with the same title apart.
+const hashLength = 8
+const maxTitle = maxFilename - len(`.webloc`) - len(" ") - hashLength
+
+// spotlightStateFile is kept in the index directory to remember where the
+// last run left off.
+const spotlightStateFile = ".pocket-spotlight.json"
+
+var spotlightItemTemplate = template.Must(template.New("item").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+    <key>URL</key>
+    <string>{{- .URL | html -}}</string>
+</dict>
+</plist>`,
+))
+
+var (
+	badChars      = regexp.MustCompile(`[^a-zA-Z0-9'". _-|()[]`)
+	repeatSpace   = regexp.MustCompile(`\s+`)
+	leadingNoise  = regexp.MustCompile(`^[ \t_-]+`)
+	trailingNoise = regexp.MustCompile(`[ \t_-]+$`)
+)
+
+// lookupSpotlightTool finds one of the Mac OS X tools the spotlight command
+// runs in $PATH.
+func lookupSpotlightTool(name string) (string, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("%s not found in $PATH, it is needed to build the spotlight index", name)
+	}
+	return path, nil
+}
+
+// convertPlist converts a written .webloc file into a binary plist.
+func convertPlist(plutil, path string) error {
+	out, err := exec.Command(plutil, "-convert", "binary1", path).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("plutil: %v: %s", err, out)
+	}
+	return nil
+}
+
+// importSpotlight asks spotlight to index dir.
+func importSpotlight(mdimport, dir string) error {
+	out, err := exec.Command(mdimport, dir).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("mdimport: %v: %s", err, out)
+	}
+	return nil
+}
+
+// spotlightState is what the spotlight command persists between runs: the
+// since cursor of the last retrieve and the file written for each item.
+type spotlightState struct {
+	Since int64             `json:"since"`
+	Files map[string]string `json:"files"`
+}
+
+func commandSpotlight(arguments map[string]interface{}, client *api.Client) error {
+	var indexDir string
+	if dir, ok := arguments["--indexdir"].(string); ok {
+		indexDir = dir
+	} else {
+		// NOTE: This must not be a hidden path or spotlight won't index it
+		home := os.Getenv("HOME")
+		if home == "" {
+			return fmt.Errorf("$HOME not set")
+		}
+		indexDir = filepath.Join(home, "Library/Caches/Metadata/go-pocket")
+	}
+
+	itemTemplate := spotlightItemTemplate
+	if path, ok := arguments["--template-file"].(string); ok {
+		var err error
+		itemTemplate, err = template.New(filepath.Base(path)).Funcs(templateFuncs).ParseFiles(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	plutil, err := lookupSpotlightTool("plutil")
+	if err != nil {
+		return err
+	}
+	noIndex, _ := arguments["--no-index"].(bool)
+	var mdimport string
+	if !noIndex {
+		mdimport, err = lookupSpotlightTool("mdimport")
+		if err != nil {
+			return err
+		}
+	}
+
+	statePath := filepath.Join(indexDir, spotlightStateFile)
+
+	state := &spotlightState{}
+	incremental, _ := arguments["--incremental"].(bool)
+	if incremental {
+		err = loadJSONFromFile(statePath, state)
+		if os.IsNotExist(err) {
+			incremental = false
+		} else if err != nil {
+			return err
+		}
+	}
+
+	options := &api.RetrieveOption{
+		State: api.StateAll,
+	}
+	if incremental {
+		options.Since = state.Since
+	}
+
+	res, err := client.Retrieve(options)
+	if err != nil {
+		return err
+	}
+
+	if !incremental {
+		state = &spotlightState{}
+		err = os.RemoveAll(indexDir)
+		if err != nil {
+			return err
+		}
+	}
+	if state.Files == nil {
+		state.Files = map[string]string{}
+	}
+
+	err = os.MkdirAll(indexDir, 0700)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range res.Items() {
+		id := strconv.FormatInt(item.ItemID, 10)
+
+		if old, ok := state.Files[id]; ok {
+			err = os.Remove(filepath.Join(indexDir, old))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			delete(state.Files, id)
+		}
+		if item.Status == api.ItemStatusDeleted {
+			continue
+		}
+
+		fname, err := spotlightFilename(item)
+		if err != nil {
+			return err
+		}
+		err = writeSpotlightItem(filepath.Join(indexDir, fname), itemTemplate, plutil, item)
+		if err != nil {
+			return err
+		}
+		state.Files[id] = fname
+	}
+
+	state.Since = res.Since
+	err = saveJSONToFile(statePath, state)
+	if err != nil {
+		return err
+	}
+
+	if noIndex {
+		return nil
+	}
+	return importSpotlight(mdimport, indexDir)
+}
+
+// spotlightFilename returns the name of the .webloc file for item, derived
+// from its title and suffixed with a short hash of its URL.
+func spotlightFilename(item api.Item) (string, error) {
+	h := sha256.New()
+	_, err := h.Write([]byte(item.URL()))
+	if err != nil {
+		return "", fmt.Errorf("Error calculating hash: %v", err)
+	}
+	fname := item.Title()
+	fname = badChars.ReplaceAllString(fname, "")
+	fname = repeatSpace.ReplaceAllString(fname, " ")
+	fname = leadingNoise.ReplaceAllString(fname, "")
+	fname = trailingNoise.ReplaceAllString(fname, "")
+	fnameRunes := []rune(fname)
+	if len(fnameRunes) > maxTitle {
+		fname = string(fnameRunes[0:maxTitle])
+	}
+	sum := fmt.Sprintf("%x", h.Sum(nil))[:hashLength]
+	if fname == "" {
+		return fmt.Sprintf("%s.webloc", sum), nil
+	}
+	return fmt.Sprintf("%s %s.webloc", fname, sum), nil
+}
+
+func writeSpotlightItem(fpath string, itemTemplate *template.Template, plutil string, item api.Item) error {
+	fout, err := os.Create(fpath)
+	if err != nil {
+		return err
+	}
+	err = itemTemplate.Execute(fout, item)
+	if err != nil {
+		fout.Close()
+		return err
+	}
+	err = fout.Close()
+	if err != nil {
+		return err
+	}
+	return convertPlist(plutil, fpath)
+}
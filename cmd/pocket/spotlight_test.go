@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// stubSpotlightTools puts fake plutil and mdimport commands, which only exist
+// on Mac OS X, in a directory which replaces $PATH until the returned function
+// is called. mdimport records the directory it was asked to index in a file
+// named mdimport.log next to the commands.
+func stubSpotlightTools(tools ...string) (string, func()) {
+	if len(tools) == 0 {
+		tools = []string{"plutil", "mdimport"}
+	}
+
+	bin, err := ioutil.TempDir("", "pocket-bin")
+	Expect(err).To(BeNil())
+
+	scripts := map[string]string{
+		"plutil":   "#!/bin/sh\nexit 0\n",
+		"mdimport": "#!/bin/sh\necho \"$1\" > \"${0%/*}/mdimport.log\"\n",
+	}
+	for _, tool := range tools {
+		Expect(ioutil.WriteFile(filepath.Join(bin, tool), []byte(scripts[tool]), 0700)).To(Succeed())
+	}
+
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", bin)
+	return bin, func() {
+		os.Setenv("PATH", path)
+		os.RemoveAll(bin)
+	}
+}
+
+func spotlightFiles(dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "*.webloc"))
+	Expect(err).To(BeNil())
+	for i, file := range files {
+		files[i] = filepath.Base(file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestCommandSpotlightIncremental(t *testing.T) {
+	RegisterTestingT(t)
+
+	_, restore := stubSpotlightTools()
+	defer restore()
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	var sinces []int64
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Since int64 `json:"since"`
+		}
+		Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+		sinces = append(sinces, body.Since)
+
+		if body.Since == 0 {
+			w.Write([]byte(`{"list":{
+				"1":{"item_id":"1","given_title":"First","given_url":"https://example.com/1","status":"0"},
+				"2":{"item_id":"2","given_title":"Second","given_url":"https://example.com/2","status":"1"}
+			},"status":1,"since":100}`))
+			return
+		}
+		w.Write([]byte(`{"list":{
+			"1":{"item_id":"1","given_title":"First, renamed","given_url":"https://example.com/1","status":"0"},
+			"2":{"item_id":"2","status":"2"},
+			"3":{"item_id":"3","given_title":"Third","given_url":"https://example.com/3","status":"0"}
+		},"status":1,"since":200}`))
+	})
+	defer ts.Close()
+
+	arguments, err := parseArguments([]string{"spotlight", "--indexdir", dir, "--incremental"})
+	Expect(err).To(BeNil())
+
+	Expect(commandSpotlight(arguments, client)).To(Succeed())
+	Expect(spotlightFiles(dir)).To(Equal([]string{"First f2f97841.webloc", "Second 10534fd4.webloc"}))
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "First f2f97841.webloc"))
+	Expect(err).To(BeNil())
+	Expect(string(content)).To(ContainSubstring("<string>https://example.com/1</string>"))
+
+	Expect(commandSpotlight(arguments, client)).To(Succeed())
+	Expect(spotlightFiles(dir)).To(Equal([]string{"First renamed f2f97841.webloc", "Third 2d395023.webloc"}))
+	Expect(sinces).To(Equal([]int64{0, 100}))
+
+	state := &spotlightState{}
+	Expect(loadJSONFromFile(filepath.Join(dir, spotlightStateFile), state)).To(Succeed())
+	Expect(state.Since).To(Equal(int64(200)))
+	Expect(state.Files).To(Equal(map[string]string{
+		"1": "First renamed f2f97841.webloc",
+		"3": "Third 2d395023.webloc",
+	}))
+}
+
+func TestCommandSpotlightFullRebuild(t *testing.T) {
+	RegisterTestingT(t)
+
+	_, restore := stubSpotlightTools()
+	defer restore()
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	Expect(ioutil.WriteFile(filepath.Join(dir, "Stale.webloc"), nil, 0600)).To(Succeed())
+
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testList))
+	})
+	defer ts.Close()
+
+	arguments, err := parseArguments([]string{"spotlight", "--indexdir", dir})
+	Expect(err).To(BeNil())
+
+	Expect(commandSpotlight(arguments, client)).To(Succeed())
+	Expect(spotlightFiles(dir)).To(Equal([]string{"First f2f97841.webloc", "Second with a comma 10534fd4.webloc"}))
+}
+
+func TestCommandSpotlightFullRebuildFailingRetrieve(t *testing.T) {
+	RegisterTestingT(t)
+
+	_, restore := stubSpotlightTools()
+	defer restore()
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	Expect(ioutil.WriteFile(filepath.Join(dir, "Existing.webloc"), nil, 0600)).To(Succeed())
+
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer ts.Close()
+
+	arguments, err := parseArguments([]string{"spotlight", "--indexdir", dir})
+	Expect(err).To(BeNil())
+
+	Expect(commandSpotlight(arguments, client)).NotTo(Succeed())
+	Expect(spotlightFiles(dir)).To(Equal([]string{"Existing.webloc"}))
+}
+
+func TestCommandSpotlightSameTitle(t *testing.T) {
+	RegisterTestingT(t)
+
+	_, restore := stubSpotlightTools()
+	defer restore()
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"list":{
+			"1":{"item_id":"1","given_title":"Same","given_url":"https://example.com/1"},
+			"2":{"item_id":"2","given_title":"Same","given_url":"https://example.com/2"}
+		},"status":1}`))
+	})
+	defer ts.Close()
+
+	arguments, err := parseArguments([]string{"spotlight", "--indexdir", dir})
+	Expect(err).To(BeNil())
+
+	Expect(commandSpotlight(arguments, client)).To(Succeed())
+	Expect(spotlightFiles(dir)).To(Equal([]string{"Same 10534fd4.webloc", "Same f2f97841.webloc"}))
+}
+
+func TestCommandSpotlightTemplateFile(t *testing.T) {
+	RegisterTestingT(t)
+
+	_, restore := stubSpotlightTools()
+	defer restore()
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	tmpl := filepath.Join(dir, "item.tmpl")
+	Expect(ioutil.WriteFile(tmpl, []byte(`{{.ItemID}} {{.URL}}`), 0600)).To(Succeed())
+	indexDir := filepath.Join(dir, "index")
+
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testList))
+	})
+	defer ts.Close()
+
+	arguments, err := parseArguments([]string{"spotlight", "--indexdir", indexDir, "--template-file", tmpl})
+	Expect(err).To(BeNil())
+
+	Expect(commandSpotlight(arguments, client)).To(Succeed())
+
+	content, err := ioutil.ReadFile(filepath.Join(indexDir, "First f2f97841.webloc"))
+	Expect(err).To(BeNil())
+	Expect(string(content)).To(Equal("1 https://example.com/1"))
+}
+
+func TestCommandSpotlightInvalidTemplateFile(t *testing.T) {
+	RegisterTestingT(t)
+
+	_, restore := stubSpotlightTools()
+	defer restore()
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	tmpl := filepath.Join(dir, "item.tmpl")
+	Expect(ioutil.WriteFile(tmpl, []byte(`{{.URL`), 0600)).To(Succeed())
+
+	requests := 0
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(testList))
+	})
+	defer ts.Close()
+
+	arguments, err := parseArguments([]string{"spotlight", "--indexdir", filepath.Join(dir, "index"), "--template-file", tmpl})
+	Expect(err).To(BeNil())
+
+	Expect(commandSpotlight(arguments, client)).NotTo(Succeed())
+	Expect(requests).To(Equal(0))
+}
+
+func TestCommandSpotlightIndexes(t *testing.T) {
+	RegisterTestingT(t)
+
+	bin, restore := stubSpotlightTools()
+	defer restore()
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testList))
+	})
+	defer ts.Close()
+
+	arguments, err := parseArguments([]string{"spotlight", "--indexdir", dir})
+	Expect(err).To(BeNil())
+
+	Expect(commandSpotlight(arguments, client)).To(Succeed())
+
+	indexed, err := ioutil.ReadFile(filepath.Join(bin, "mdimport.log"))
+	Expect(err).To(BeNil())
+	Expect(string(indexed)).To(Equal(dir + "\n"))
+}
+
+func TestCommandSpotlightMissingTools(t *testing.T) {
+	RegisterTestingT(t)
+
+	bin, restore := stubSpotlightTools("plutil")
+	defer restore()
+
+	dir, err := ioutil.TempDir("", "pocket")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	client, ts := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testList))
+	})
+	defer ts.Close()
+
+	arguments, err := parseArguments([]string{"spotlight", "--indexdir", dir})
+	Expect(err).To(BeNil())
+
+	err = commandSpotlight(arguments, client)
+	Expect(err).To(MatchError(ContainSubstring("mdimport not found in $PATH")))
+
+	arguments, err = parseArguments([]string{"spotlight", "--indexdir", dir, "--no-index"})
+	Expect(err).To(BeNil())
+
+	Expect(commandSpotlight(arguments, client)).To(Succeed())
+	Expect(spotlightFiles(dir)).To(HaveLen(2))
+	Expect(filepath.Join(bin, "mdimport.log")).NotTo(BeAnExistingFile())
+
+	os.Setenv("PATH", "")
+	err = commandSpotlight(arguments, client)
+	Expect(err).To(MatchError(ContainSubstring("plutil not found in $PATH")))
+}